@@ -1,13 +1,14 @@
 package main
 
 import (
-	"context"
+	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
-	"github.com/dstotijn/go-notion"
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
@@ -15,10 +16,28 @@ import (
 	"github.com/slack-go/slack"
 	"github.com/webuild-community/core/handler"
 	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/abuse"
+	"github.com/webuild-community/core/service/badge"
+	"github.com/webuild-community/core/service/channel"
 	"github.com/webuild-community/core/service/command"
+	"github.com/webuild-community/core/service/consumer"
+	"github.com/webuild-community/core/service/decay"
+	"github.com/webuild-community/core/service/dedup"
 	"github.com/webuild-community/core/service/event"
 	"github.com/webuild-community/core/service/item"
+	"github.com/webuild-community/core/service/kudos"
+	"github.com/webuild-community/core/service/leaderboard"
+	"github.com/webuild-community/core/service/level"
+	"github.com/webuild-community/core/service/mint"
+	"github.com/webuild-community/core/service/notification"
+	"github.com/webuild-community/core/service/order"
+	"github.com/webuild-community/core/service/quest"
 	"github.com/webuild-community/core/service/queue"
+	"github.com/webuild-community/core/service/rule"
+	"github.com/webuild-community/core/service/season"
+	"github.com/webuild-community/core/service/streak"
+	"github.com/webuild-community/core/service/tip"
+	"github.com/webuild-community/core/service/transaction"
 	"github.com/webuild-community/core/service/user"
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
@@ -50,99 +69,177 @@ func main() {
 		logger.Panic("cannot connect to db", zap.Error(err))
 	}
 
-	notionClient := notion.NewClient(os.Getenv("NOTION_SECRET_KEY"))
-
+	// ranking scores start from lifetime exp the first time they are migrated
+	seedRanking := !db.Migrator().HasColumn(&model.User{}, "RankingScore")
+	// purchases made before orders were tracked have already been handed out
+	seedOrders := !db.Migrator().HasColumn(&model.Transaction{}, "Status")
 	if err := db.AutoMigrate(
 		&model.User{},
 		&model.Item{},
 		&model.Transaction{},
+		&model.Account{},
+		&model.JournalEntry{},
+		&model.Posting{},
+		&model.QueueMessage{},
+		&model.QueueDeadLetter{},
+		&model.ProcessedEvent{},
+		&model.AbuseFlag{},
+		&model.ExpEvent{},
+		&model.Season{},
+		&model.SeasonStanding{},
+		&model.Badge{},
+		&model.UserBadge{},
+		&model.Kudos{},
+		&model.Quest{},
+		&model.QuestProgress{},
+		&model.ChannelSetting{},
 	); err != nil {
 		logger.Panic("cannot migrate db", zap.Error(err))
 	}
+	if seedRanking {
+		if err := db.Exec(`UPDATE "user" SET ranking_score = exp`).Error; err != nil {
+			logger.Panic("cannot seed ranking scores", zap.Error(err))
+		}
+	}
+	if seedOrders {
+		if err := db.Exec(`UPDATE "transaction" SET status = ?`, model.OrderFulfilled).Error; err != nil {
+			logger.Panic("cannot seed order statuses", zap.Error(err))
+		}
+	}
+
+	ledger := transaction.NewPGService(db)
+	if err := ledger.Backfill(); err != nil {
+		logger.Panic("cannot backfill ledger", zap.Error(err))
+	}
+
+	tipDailyLimit, _ := strconv.ParseFloat(os.Getenv("TIP_DAILY_LIMIT"), 64)
+	tipSvc := tip.NewSlackService(logger, db, slackClient, ledger, tipDailyLimit)
+
+	decayCfg := decay.NewConfigFromEnv()
+	decaySvc := decay.NewPGService(db, decayCfg)
+	leaderboardSvc := leaderboard.NewPGService(db, decayCfg.Enabled())
+	seasonRewards, err := season.NewRewardsFromEnv()
+	if err != nil {
+		logger.Panic("cannot read season rewards", zap.Error(err))
+	}
+	seasonSvc := season.NewSlackService(logger, db, slackClient, ledger, seasonRewards)
+	mintSvc := mint.NewSlackService(logger, slackClient, ledger, leaderboardSvc, mint.NewRulesFromEnv())
+
+	var q queue.Service
+	if os.Getenv("QUEUE_DRIVER") == "memory" {
+		q = queue.NewQueueService()
+	} else {
+		q = queue.NewPGService(db, func() interface{} { return &model.ExpEvent{} }, 10*time.Minute, 5)
+	}
+	// the item table is the source of truth, notion or a file are optional catalogs to import from
+	catalog, err := item.NewCatalogFromEnv(logger)
+	if err != nil {
+		logger.Panic("cannot build item catalog", zap.Error(err))
+	}
+	if v, ok := catalog.(item.Validator); ok {
+		var schemaErr *item.SchemaError
+		if err := v.Validate(); errors.As(err, &schemaErr) {
+			logger.Panic("cannot use item catalog", zap.Error(err))
+		} else if err != nil {
+			logger.Error("cannot validate item catalog", zap.Error(err))
+		}
+	}
+	var itemSyncer item.Syncer
+	if catalog != nil {
+		itemSyncer = item.NewSyncer(logger, db, catalog)
+		if _, err := itemSyncer.Import(); err != nil {
+			logger.Error("cannot import items", zap.Error(err))
+		}
+	}
+	commandSvc := command.NewSlackService(logger, db, slackClient, itemSyncer)
+	kudosSvc := kudos.NewSlackService(logger, db, slackClient, q, kudos.NewConfigFromEnv())
+	questSvc := quest.NewSlackService(logger, db, slackClient, q, ledger)
+	curve, err := level.NewFromEnv()
+	if err != nil {
+		logger.Panic("cannot build level curve", zap.Error(err))
+	}
+	ruleSvc, err := rule.NewFileService(os.Getenv("EXP_RULES_FILE"))
+	if err != nil {
+		logger.Panic("cannot load exp rules", zap.Error(err))
+	}
+	userSvc := user.NewPGService(db, curve, ruleSvc)
+	if err := userSvc.Backfill(); err != nil {
+		logger.Panic("cannot backfill exp history", zap.Error(err))
+	}
+	itemSvc := item.NewPGService(logger, db, ledger)
+	orderSvc := order.NewSlackService(logger, db, slackClient, ledger, os.Getenv("FULFILMENT_CHANNEL"))
+	badges, err := badge.LoadBadges(os.Getenv("BADGES_FILE"))
+	if err != nil {
+		logger.Panic("cannot load badges", zap.Error(err))
+	}
+	badgeSvc := badge.NewSlackService(logger, db, slackClient, badges)
+	if err := badgeSvc.Sync(); err != nil {
+		logger.Panic("cannot sync badges", zap.Error(err))
+	}
+
+	notifySvc := notification.NewSlackService(logger, db, slackClient, notification.NewConfigFromEnv())
+	eventSvc := event.NewSlackService(logger, db, slackClient, itemSvc, tipSvc, curve, notifySvc, leaderboardSvc, badgeSvc, kudosSvc, questSvc)
+
+	abuseSvc := abuse.NewSlackService(logger, db, slackClient, abuse.NewLimitsFromEnv(), os.Getenv("ABUSE_REPORT_CHANNEL"))
+	streakSvc := streak.NewPGService(db, abuseSvc, streak.NewMultipliersFromEnv())
 
-	q := queue.NewQueueService()
-	commandSvc := command.NewSlackService(logger, db, slackClient)
-	userSvc := user.NewPGService(db)
-	itemSvc := item.NewPGService(logger, notionClient, db)
-	eventSvc := event.NewSlackService(logger, db, slackClient, notionClient)
+	batchSize, err := strconv.Atoi(os.Getenv("CONSUMER_BATCH_SIZE"))
+	if err != nil || batchSize <= 0 {
+		batchSize = 500
+	}
+	profileTTL, err := time.ParseDuration(os.Getenv("PROFILE_SYNC_TTL"))
+	if err != nil {
+		profileTTL = 24 * time.Hour
+	}
+	consumerSvc := consumer.NewSlackService(logger, db, slackClient, q, userSvc, mintSvc, notifySvc, badgeSvc, streakSvc, questSvc, abuseSvc, batchSize, profileTTL)
 
-	c := cron.New()
+	dedupTTL, err := time.ParseDuration(os.Getenv("EVENT_DEDUP_TTL"))
+	if err != nil {
+		dedupTTL = time.Hour
+	}
+	dedupSvc := dedup.NewPGService(db, dedupTTL)
+
+	channelSvc := channel.NewPGService(db)
+
+	// skip a run while the previous one is still consuming so batches never overlap
+	c := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
 	c.AddFunc("@every 0h5m00s", func() {
-		// this will avoid multiple cronjobs may read same data at sometime
 		logger.Info("start handling msg")
-		if !q.GetIsConsuming() {
-			q.SetIsConsuming(true)
-			var next interface{}
-
-			for e := q.Consume(); e != nil; e = next {
-				if u, ok := e.(*model.User); ok {
-					sUser, err := slackClient.GetUserInfo(u.ID)
-					if err != nil {
-						logger.Error("cannot get slack user info", zap.Error(err), zap.String("user_id", u.ID))
-						continue
-					}
-					if sUser.IsBot {
-						continue
-					}
-					_, _, err = userSvc.Update(u.ID, map[string]interface{}{
-						"exp":            u.Exp,
-						"first_name":     sUser.Profile.FirstName,
-						"last_name":      sUser.Profile.LastName,
-						"real_name":      sUser.Profile.RealName,
-						"display_name":   sUser.Profile.DisplayName,
-						"tz":             sUser.TZ,
-						"image_original": sUser.Profile.ImageOriginal,
-						"slack_email":    sUser.Profile.Email,
-					})
-					if err != nil {
-						logger.Error("cannot update user exp", zap.Error(err), zap.String("user_id", u.ID))
-					}
-					// if isLevelUp && u.SlackChannel != "" {
-					// 	slackClient.PostMessage(u.SlackChannel, slack.MsgOptionText(fmt.Sprintf("User %v is level up!", u.ID), false))
-					// }
-				}
-				next = q.Consume()
-				time.Sleep(100 * time.Millisecond)
-			}
-
-			q.SetIsConsuming(false)
+		if err := consumerSvc.Consume(); err != nil {
+			logger.Error("cannot consume queued messages", zap.Error(err))
 		}
 		logger.Info("end handling msg")
 	})
 
-	c.AddFunc("@every 0h1m00s", func() {
-		logger.Info("start syncing redeem")
-		isExpired := false
-		items, err := notionClient.QueryDatabase(context.Background(), os.Getenv("NOTION_DATABASE_ID"), &notion.DatabaseQuery{Filter: &notion.DatabaseQueryFilter{
-			Property: "Expired",
-			Checkbox: &notion.CheckboxDatabaseQueryFilter{Equals: &isExpired},
-		}})
+	if itemSyncer != nil {
+		c.AddFunc("@every 0h1m00s", func() {
+			logger.Info("start syncing redeem")
+			if err := itemSyncer.Export(); err != nil {
+				logger.Error("cannot export redeemed counts", zap.Error(err))
+			}
+			logger.Info("end syncing redeem")
+		})
+	}
+	c.AddFunc("@weekly", func() {
+		logger.Info("start minting weekly bonus")
+		if err := mintSvc.WeeklyBonus(); err != nil {
+			logger.Error("cannot mint weekly bonus", zap.Error(err))
+		}
+		logger.Info("end minting weekly bonus")
+	})
+	c.AddFunc("@daily", func() {
+		n, err := decaySvc.Apply(time.Now())
 		if err != nil {
-			logger.Error("cannot fetch items", zap.Error(err))
+			logger.Error("cannot decay ranking scores", zap.Error(err))
 			return
 		}
-
-		for _, v := range items.Results {
-			var count int64
-			if err := db.Model(&model.Transaction{}).Where("item_id = ?", v.ID).Count(&count).Error; err != nil {
-				logger.Error("cannot count transaction", zap.Error(err), zap.String("item_id", v.ID))
-				continue
-			}
-			if count == 0 {
-				continue
-			}
-
-			redeemed := float64(count)
-			notionClient.UpdatePageProps(context.Background(),
-				v.ID,
-				notion.UpdatePageParams{DatabasePageProperties: &notion.DatabasePageProperties{
-					"Redeemed": notion.DatabasePageProperty{
-						Type:   "number",
-						Number: &redeemed,
-					}}},
-			)
+		logger.Info("decayed ranking scores", zap.Int64("users", n))
+	})
+	c.AddFunc("@hourly", func() {
+		if err := dedupSvc.Purge(); err != nil {
+			logger.Error("cannot purge processed events", zap.Error(err))
 		}
-		logger.Info("end syncing redeem")
+		abuseSvc.Prune(time.Now())
 	})
 	c.Start()
 
@@ -156,10 +253,23 @@ func main() {
 		return c.String(http.StatusOK, "ok")
 	})
 
-	handler.NewEventHandler(e, logger, q, eventSvc, userSvc)
-	handler.NewCommandHandler(e, logger, q, commandSvc, userSvc)
-	handler.NewInteractiveHandler(e, logger, q, userSvc, itemSvc)
-	handler.NewAuthorizeHandler(e, logger, db, slackClient)
+	handler.NewEventHandler(e, logger, q, eventSvc, userSvc, dedupSvc, ruleSvc, abuseSvc, channelSvc)
+	handler.NewCommandHandler(e, logger, q, commandSvc, userSvc, tipSvc, seasonSvc, kudosSvc, questSvc, channelSvc)
+	handler.NewInteractiveHandler(e, logger, q, userSvc, itemSvc, badgeSvc, orderSvc)
+	handler.NewAuthorizeHandler(e, logger, db, slackClient, badgeSvc, questSvc)
+
+	// metrics are served on their own listener, away from the public port slack calls
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = "127.0.0.1:8081"
+	}
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+		if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+			logger.Error("cannot serve metrics", zap.Error(err), zap.String("addr", metricsAddr))
+		}
+	}()
 
 	e.Logger.Fatal(e.Start(":8080"))
 }
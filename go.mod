@@ -5,7 +5,6 @@ go 1.16
 require (
 	github.com/dstotijn/go-notion v0.3.3
 	github.com/joho/godotenv v1.3.0
-	github.com/k0kubun/pp v3.0.1+incompatible
 	github.com/labstack/echo v3.3.10+incompatible
 	github.com/labstack/gommon v0.3.0 // indirect
 	github.com/mattn/go-colorable v0.1.8 // indirect
@@ -17,6 +16,7 @@ require (
 	golang.org/x/net v0.0.0-20210405180319-a5a99cb37ef4 // indirect
 	golang.org/x/sys v0.0.0-20210403161142-5e06dd20ab57 // indirect
 	golang.org/x/text v0.3.6 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 	gorm.io/driver/postgres v1.1.0
 	gorm.io/gorm v1.21.10
-)
\ No newline at end of file
+)
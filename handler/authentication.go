@@ -12,6 +12,8 @@ import (
 	"github.com/labstack/echo"
 	"github.com/slack-go/slack"
 	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/badge"
+	"github.com/webuild-community/core/service/quest"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -22,6 +24,8 @@ type AuthorizeHandler struct {
 	logger       *zap.Logger
 	db           *gorm.DB
 	slack        *slack.Client
+	badgeSvc     badge.Service
+	questSvc     quest.Service
 }
 
 func NewAuthorizeHandler(
@@ -29,6 +33,8 @@ func NewAuthorizeHandler(
 	logger *zap.Logger,
 	db *gorm.DB,
 	slack *slack.Client,
+	badgeSvc badge.Service,
+	questSvc quest.Service,
 ) {
 	clientID := os.Getenv("GITHUB_CLIENT_ID")
 	if len(clientID) == 0 {
@@ -45,6 +51,8 @@ func NewAuthorizeHandler(
 		logger:       logger,
 		db:           db,
 		slack:        slack,
+		badgeSvc:     badgeSvc,
+		questSvc:     questSvc,
 	}
 
 	e.GET("/callback/github/auth", handler.handleGithubCallback)
@@ -185,6 +193,13 @@ func (h *AuthorizeHandler) handleGithubCallback(ctx echo.Context) error {
 		return err
 	}
 
+	if _, err := h.badgeSvc.Evaluate(user.ID); err != nil {
+		h.logger.Error("evaluate badges failed", zap.Error(err))
+	}
+	if err := h.questSvc.GithubLinked(user.ID); err != nil {
+		h.logger.Error("track quests failed", zap.Error(err))
+	}
+
 	return h.sendSlackRegiterSuccessMsg(&user)
 }
 
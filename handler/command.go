@@ -2,28 +2,54 @@ package handler
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo"
 	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/channel"
 	"github.com/webuild-community/core/service/command"
+	"github.com/webuild-community/core/service/kudos"
+	"github.com/webuild-community/core/service/quest"
 	"github.com/webuild-community/core/service/queue"
+	"github.com/webuild-community/core/service/season"
+	"github.com/webuild-community/core/service/tip"
 	"github.com/webuild-community/core/service/user"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+var (
+	mentionRe = regexp.MustCompile(`^<@([A-Z0-9]+)(\|[^>]*)?>$`)
+	channelRe = regexp.MustCompile(`^<#([A-Z0-9]+)(\|[^>]*)?>$`)
+)
+
 type CommandHandler struct {
 	queueSvc   queue.Service
 	commandSvc command.Service
 	userSvc    user.Service
+	tipSvc     tip.Service
+	seasonSvc  season.Service
+	kudosSvc   kudos.Service
+	questSvc   quest.Service
+	channelSvc channel.Service
 	logger     *zap.Logger
 }
 
-func NewCommandHandler(e *echo.Echo, logger *zap.Logger, queueSvc queue.Service, commandSvc command.Service, userSvc user.Service) {
+func NewCommandHandler(e *echo.Echo, logger *zap.Logger, queueSvc queue.Service, commandSvc command.Service, userSvc user.Service, tipSvc tip.Service, seasonSvc season.Service, kudosSvc kudos.Service, questSvc quest.Service, channelSvc channel.Service) {
 	handler := &CommandHandler{
 		logger:     logger,
 		userSvc:    userSvc,
+		tipSvc:     tipSvc,
+		seasonSvc:  seasonSvc,
+		kudosSvc:   kudosSvc,
+		questSvc:   questSvc,
+		channelSvc: channelSvc,
 		queueSvc:   queueSvc,
 		commandSvc: commandSvc,
 	}
@@ -58,13 +84,296 @@ func (h *CommandHandler) commands(c echo.Context) error {
 			return c.String(http.StatusForbidden, "Forbidden")
 		}
 
-		if err := h.commandSvc.Sync(); err != nil {
+		n, err := h.commandSvc.Sync()
+		if err != nil {
+			if errors.Is(err, command.ErrNoItemSource) {
+				return c.String(http.StatusOK, "No item source is configured, items are managed in the database")
+			}
+			h.logger.Error("cannot sync items", zap.Error(err))
 			return c.NoContent(http.StatusInternalServerError)
 		}
 
-		return c.String(http.StatusOK, "Synced from airtable successful")
+		return c.String(http.StatusOK, fmt.Sprintf("Imported %d items", n))
+
+	case "/recompute":
+		if !user.IsAdmin {
+			return c.String(http.StatusForbidden, "Forbidden")
+		}
+
+		userID := ""
+		if s.Text != "" {
+			m := mentionRe.FindStringSubmatch(strings.TrimSpace(s.Text))
+			if m == nil {
+				return c.String(http.StatusOK, "Usage: `/recompute [@user]`")
+			}
+			userID = m[1]
+		}
+		// recomputing everyone outlasts slack's 3 second deadline, the result follows on the response url
+		go h.recompute(userID, s.ResponseURL)
+		return c.String(http.StatusOK, "Recomputing exp and levels, the result will be posted here")
+
+	case "/season":
+		return h.season(c, user.IsAdmin, s.Text)
+
+	case "/quest":
+		return h.quest(c, user.IsAdmin, s.Text)
+
+	case "/channel":
+		if !user.IsAdmin {
+			return c.String(http.StatusForbidden, "Forbidden")
+		}
+		return h.channel(c, s.Text)
+
+	case "/tip":
+		toUserID, amount, reason, err := tip.ParseArgs(s.Text)
+		if err == nil {
+			_, err = h.tipSvc.Send(s.TriggerID, s.UserID, toUserID, amount, reason)
+		}
+		if err != nil {
+			if tip.IsRejection(err) {
+				return c.String(http.StatusOK, fmt.Sprintf("Cannot send tip: %v", err))
+			}
+			h.logger.Error("cannot send tip", zap.Error(err), zap.String("user_id", s.UserID))
+			return c.String(http.StatusOK, "Please try again later")
+		}
+
+		return c.String(http.StatusOK, fmt.Sprintf("Sent %v RDF to <@%s>", amount, toUserID))
+
+	case "/kudos":
+		toUserID, reason, err := kudos.ParseArgs(s.Text)
+		if err == nil {
+			_, err = h.kudosSvc.Give(s.TriggerID, s.UserID, toUserID, s.ChannelID, reason)
+		}
+		if err != nil {
+			if kudos.IsRejection(err) {
+				return c.String(http.StatusOK, fmt.Sprintf("Cannot give kudos: %v", err))
+			}
+			h.logger.Error("cannot give kudos", zap.Error(err), zap.String("user_id", s.UserID))
+			return c.String(http.StatusOK, "Please try again later")
+		}
+
+		return c.String(http.StatusOK, fmt.Sprintf("Gave kudos to <@%s>", toUserID))
 
 	}
 
 	return c.NoContent(http.StatusInternalServerError)
 }
+
+func (h *CommandHandler) season(c echo.Context, isAdmin bool, text string) error {
+	action, name := parseCommand(text)
+	switch action {
+	case "":
+		current, err := h.seasonSvc.Current()
+		if err != nil {
+			h.logger.Error("cannot get current season", zap.Error(err))
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		if current == nil {
+			return c.String(http.StatusOK, "No season is running")
+		}
+		return c.String(http.StatusOK, fmt.Sprintf("*%s* is running since %s", current.Name, current.StartedAt.Format("2006-01-02")))
+
+	case "start":
+		if !isAdmin {
+			return c.String(http.StatusForbidden, "Forbidden")
+		}
+		if name == "" {
+			return c.String(http.StatusOK, "Usage: `/season start <name>`")
+		}
+
+		started, err := h.seasonSvc.Start(name)
+		if err != nil {
+			if errors.Is(err, season.ErrSeasonActive) {
+				return c.String(http.StatusOK, "A season is already running, end it first")
+			}
+			h.logger.Error("cannot start season", zap.Error(err))
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		return c.String(http.StatusOK, fmt.Sprintf("*%s* has started, season exp was reset", started.Name))
+
+	case "end":
+		if !isAdmin {
+			return c.String(http.StatusForbidden, "Forbidden")
+		}
+
+		ended, standings, err := h.seasonSvc.End()
+		if err != nil {
+			if errors.Is(err, season.ErrNoActiveSeason) {
+				return c.String(http.StatusOK, "No season is running")
+			}
+			h.logger.Error("cannot end season", zap.Error(err))
+			return c.NoContent(http.StatusInternalServerError)
+		}
+
+		text := fmt.Sprintf("*%s* has ended", ended.Name)
+		for i, st := range standings {
+			if i == 3 {
+				break
+			}
+			text += fmt.Sprintf("\n#%d <@%s> - %d exp", st.Rank, st.UserID, st.Exp)
+		}
+		return c.String(http.StatusOK, text)
+	}
+
+	return c.String(http.StatusOK, "Usage: `/season [start <name>|end]`")
+}
+
+func (h *CommandHandler) quest(c echo.Context, isAdmin bool, text string) error {
+	action, args := parseCommand(text)
+	switch action {
+	case "":
+		quests, err := h.questSvc.Active()
+		if err != nil {
+			h.logger.Error("cannot list quests", zap.Error(err))
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		if len(quests) == 0 {
+			return c.String(http.StatusOK, "No quest is running")
+		}
+		text := "Active quests:"
+		for _, q := range quests {
+			text += fmt.Sprintf("\n#%d *%s* - %s x%d", q.ID, q.Name, q.EventType, q.Target)
+		}
+		return c.String(http.StatusOK, text)
+
+	case "add":
+		if !isAdmin {
+			return c.String(http.StatusForbidden, "Forbidden")
+		}
+
+		q, err := quest.Parse(args)
+		if err != nil {
+			return c.String(http.StatusOK, fmt.Sprintf("Invalid quest: %v", err))
+		}
+		if err := h.questSvc.Add(q); err != nil {
+			if errors.Is(err, quest.ErrInvalidQuest) {
+				return c.String(http.StatusOK, fmt.Sprintf("Invalid quest: %v", err))
+			}
+			h.logger.Error("cannot add quest", zap.Error(err))
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		return c.String(http.StatusOK, fmt.Sprintf("Quest #%d *%s* added", q.ID, q.Name))
+
+	case "end":
+		if !isAdmin {
+			return c.String(http.StatusForbidden, "Forbidden")
+		}
+
+		id, err := strconv.ParseUint(args, 10, 64)
+		if err != nil {
+			return c.String(http.StatusOK, "Usage: `/quest end <id>`")
+		}
+		if err := h.questSvc.End(uint(id)); err != nil {
+			if errors.Is(err, quest.ErrQuestNotFound) {
+				return c.String(http.StatusOK, "No running quest with this id")
+			}
+			h.logger.Error("cannot end quest", zap.Error(err))
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		return c.String(http.StatusOK, fmt.Sprintf("Quest #%d has ended", id))
+	}
+
+	return c.String(http.StatusOK, "Usage: `/quest [add <json>|end <id>]`")
+}
+
+const channelUsage = "Usage: `/channel [#channel [multiplier <x>|exclude on|off|readonly on|off|reset]]`"
+
+func (h *CommandHandler) channel(c echo.Context, text string) error {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		settings, err := h.channelSvc.List()
+		if err != nil {
+			h.logger.Error("cannot list channel settings", zap.Error(err))
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		if len(settings) == 0 {
+			return c.String(http.StatusOK, "Every channel uses the default settings")
+		}
+		text := "Channel settings:"
+		for _, st := range settings {
+			text += "\n" + describeChannel(st)
+		}
+		return c.String(http.StatusOK, text)
+	}
+
+	m := channelRe.FindStringSubmatch(fields[0])
+	if m == nil {
+		return c.String(http.StatusOK, channelUsage)
+	}
+	st, err := h.channelSvc.Get(m[1])
+	if err != nil {
+		h.logger.Error("cannot get channel setting", zap.Error(err), zap.String("channel_id", m[1]))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	if len(fields) == 1 {
+		return c.String(http.StatusOK, describeChannel(st))
+	}
+
+	if fields[1] == "reset" {
+		if err := h.channelSvc.Reset(st.ChannelID); err != nil {
+			h.logger.Error("cannot reset channel setting", zap.Error(err), zap.String("channel_id", st.ChannelID))
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		return c.String(http.StatusOK, describeChannel(channel.Default(st.ChannelID)))
+	}
+	if len(fields) != 3 {
+		return c.String(http.StatusOK, channelUsage)
+	}
+
+	switch fields[1] {
+	case "multiplier":
+		multiplier, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil || math.IsNaN(multiplier) || math.IsInf(multiplier, 0) || multiplier < 0 {
+			return c.String(http.StatusOK, "The multiplier must be a number of 0 or more")
+		}
+		st.Multiplier = multiplier
+	case "exclude":
+		if fields[2] != "on" && fields[2] != "off" {
+			return c.String(http.StatusOK, channelUsage)
+		}
+		st.Excluded = fields[2] == "on"
+	case "readonly":
+		if fields[2] != "on" && fields[2] != "off" {
+			return c.String(http.StatusOK, channelUsage)
+		}
+		st.ReadOnly = fields[2] == "on"
+	default:
+		return c.String(http.StatusOK, channelUsage)
+	}
+
+	if err := h.channelSvc.Save(st); err != nil {
+		h.logger.Error("cannot save channel setting", zap.Error(err), zap.String("channel_id", st.ChannelID))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	return c.String(http.StatusOK, describeChannel(st))
+}
+
+func describeChannel(st model.ChannelSetting) string {
+	text := fmt.Sprintf("<#%s>: exp x%v", st.ChannelID, st.Multiplier)
+	if st.Excluded {
+		text += ", excluded"
+	}
+	if st.ReadOnly {
+		text += ", read-only"
+	}
+	return text
+}
+
+// recompute rates exp events again and posts the outcome to responseURL
+func (h *CommandHandler) recompute(userID, responseURL string) {
+	text := "Rated exp events with the current rules and recomputed exp and level"
+	switch err := h.userSvc.Recompute(userID); {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		text = "User not found"
+	case err != nil:
+		h.logger.Error("cannot recompute exp", zap.Error(err), zap.String("user_id", userID))
+		text = "Cannot recompute exp, please try again later"
+	}
+
+	if responseURL == "" {
+		return
+	}
+	if err := slack.PostWebhook(responseURL, &slack.WebhookMessage{Text: text}); err != nil {
+		h.logger.Error("cannot reply to /recompute", zap.Error(err))
+	}
+}
@@ -2,32 +2,47 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo"
 	"github.com/slack-go/slack/slackevents"
 	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/abuse"
+	"github.com/webuild-community/core/service/channel"
+	"github.com/webuild-community/core/service/dedup"
 	"github.com/webuild-community/core/service/event"
 	"github.com/webuild-community/core/service/queue"
+	"github.com/webuild-community/core/service/rule"
 	"github.com/webuild-community/core/service/user"
 	"go.uber.org/zap"
 )
 
 type EventHandler struct {
-	queueSvc queue.Service
-	eventSvc event.Service
-	userSvc  user.Service
-	logger   *zap.Logger
+	queueSvc   queue.Service
+	eventSvc   event.Service
+	userSvc    user.Service
+	dedupSvc   dedup.Service
+	ruleSvc    rule.Service
+	abuseSvc   abuse.Service
+	channelSvc channel.Service
+	logger     *zap.Logger
 }
 
-func NewEventHandler(e *echo.Echo, logger *zap.Logger, queueSvc queue.Service, eventSvc event.Service, userSvc user.Service) {
+func NewEventHandler(e *echo.Echo, logger *zap.Logger, queueSvc queue.Service, eventSvc event.Service, userSvc user.Service, dedupSvc dedup.Service, ruleSvc rule.Service, abuseSvc abuse.Service, channelSvc channel.Service) {
 	handler := &EventHandler{
-		logger:   logger,
-		userSvc:  userSvc,
-		queueSvc: queueSvc,
-		eventSvc: eventSvc,
+		logger:     logger,
+		userSvc:    userSvc,
+		queueSvc:   queueSvc,
+		eventSvc:   eventSvc,
+		dedupSvc:   dedupSvc,
+		ruleSvc:    ruleSvc,
+		abuseSvc:   abuseSvc,
+		channelSvc: channelSvc,
 	}
 
 	e.POST("/slack/events", handler.events)
@@ -64,8 +79,19 @@ func (h *EventHandler) events(c echo.Context) error {
 	}
 
 	if eventsAPIEvent.Type == slackevents.CallbackEvent {
+		// slack redelivers events it considers slow, acknowledge those without handling them again
+		if cb, ok := eventsAPIEvent.Data.(*slackevents.EventsAPICallbackEvent); ok && cb.EventID != "" {
+			seen, err := h.dedupSvc.Seen(cb.EventID)
+			if err != nil {
+				h.logger.Error("cannot check event id", zap.Error(err), zap.String("event_id", cb.EventID))
+			} else if seen {
+				h.logger.Info("suppressed duplicate event", zap.String("event_id", cb.EventID), zap.String("retry_num", c.Request().Header.Get("X-Slack-Retry-Num")))
+				return c.NoContent(http.StatusOK)
+			}
+		}
+
 		innerEvent := eventsAPIEvent.InnerEvent
-		exp := 1
+		now := time.Now()
 
 		switch ev := innerEvent.Data.(type) {
 		// case *slackevents.AppMentionEvent:
@@ -77,7 +103,8 @@ func (h *EventHandler) events(c echo.Context) error {
 			}
 			h.logger.Info("received event", zap.String("user_id", ev.User), zap.String("event", "MessageEvent"))
 
-			switch ev.Text {
+			command, args := parseCommand(ev.Text)
+			switch command {
 			case "$profile":
 				if err := h.eventSvc.Profile(ev.Channel, ev.User); err != nil {
 					h.logger.Error("cannot process $profile event", zap.Error(err))
@@ -91,7 +118,7 @@ func (h *EventHandler) events(c echo.Context) error {
 				return c.NoContent(http.StatusOK)
 
 			case "$top":
-				if err := h.eventSvc.Top(ev.Channel); err != nil {
+				if err := h.eventSvc.Top(ev.Channel, ev.User, args); err != nil {
 					h.logger.Error("cannot process $top event", zap.Error(err))
 				}
 				return c.NoContent(http.StatusOK)
@@ -110,14 +137,54 @@ func (h *EventHandler) events(c echo.Context) error {
 				}
 				return c.NoContent(http.StatusOK)
 
+			case "$notify":
+				if err := h.eventSvc.Notify(ev.Channel, ev.User, args); err != nil {
+					h.logger.Error("cannot process $notify event", zap.Error(err))
+				}
+				return c.NoContent(http.StatusOK)
+
+			case "$tip":
+				if err := h.eventSvc.Tip(ev.Channel, ev.User, ev.TimeStamp, args); err != nil {
+					h.logger.Error("cannot process $tip event", zap.Error(err))
+				}
+				return c.NoContent(http.StatusOK)
+
+			case "$kudos":
+				if err := h.eventSvc.Kudos(ev.Channel, ev.User, ev.TimeStamp, args); err != nil {
+					h.logger.Error("cannot process $kudos event", zap.Error(err))
+				}
+				return c.NoContent(http.StatusOK)
+
+			case "$quests":
+				if err := h.eventSvc.Quests(ev.Channel, ev.User); err != nil {
+					h.logger.Error("cannot process $quests event", zap.Error(err))
+				}
+				return c.NoContent(http.StatusOK)
+
 			}
 
-			if len(ev.Text) > 50 {
-				exp++
+			// messages in these channels are not tracked by the abuse checks either
+			if st := h.channelSetting(ev.Channel); st.Excluded || st.ReadOnly {
+				break
+			}
+			e := rule.Event{
+				Type:        rule.EventMessage,
+				Role:        rule.RoleAuthor,
+				ChannelID:   ev.Channel,
+				ThreadReply: ev.ThreadTimeStamp != "" && ev.ThreadTimeStamp != ev.TimeStamp,
+				TextLength:  len(ev.Text),
+				Time:        now,
 			}
+			res := h.ruleSvc.Evaluate(e)
+			res.Points = h.abuseSvc.Message(ev.User, ev.Channel, ev.Text, res.Points, now)
+			h.credit(ev.User, ev.TimeStamp, e, res)
 
-			if err := h.queueSvc.Add(&model.User{ID: ev.User, Exp: int64(exp), SlackChannel: ev.Channel, CreatedAt: time.Now()}); err != nil {
-				h.logger.Error("cannot add MessageEvent to queue", zap.Error(err))
+		case *slackevents.AppHomeOpenedEvent:
+			if ev.Tab != "home" {
+				break
+			}
+			if err := h.eventSvc.Home(ev.User); err != nil {
+				h.logger.Error("cannot publish app home", zap.Error(err), zap.String("user_id", ev.User))
 			}
 
 		case *slackevents.ReactionAddedEvent:
@@ -126,11 +193,18 @@ func (h *EventHandler) events(c echo.Context) error {
 			}
 			h.logger.Info("received event", zap.String("user_id", ev.User), zap.String("event", "ReactionAddedEvent"))
 
-			if err := h.queueSvc.Add(&model.User{ID: ev.ItemUser, Exp: int64(exp), CreatedAt: time.Now()}); err != nil {
-				h.logger.Error("cannot add ReactionAddedEvent to queue", zap.Error(err))
+			reaction := reactionKey(ev.User, ev.Item.Channel, ev.Item.Timestamp, ev.Reaction)
+			if !h.abuseSvc.Reaction(ev.User, ev.ItemUser, true, now) {
+				h.abuseSvc.Withhold(ev.ItemUser, reaction, now)
+				h.abuseSvc.Withhold(ev.User, reaction, now)
+				break
 			}
-			if err := h.queueSvc.Add(&model.User{ID: ev.User, Exp: int64(exp), CreatedAt: time.Now()}); err != nil {
-				h.logger.Error("cannot add ReactionAddedEvent to queue", zap.Error(err))
+			e := rule.Event{Type: rule.EventReactionAdded, ChannelID: ev.Item.Channel, Emoji: ev.Reaction, Time: now}
+			if !h.credit(ev.ItemUser, ev.Item.Timestamp, e.As(rule.RoleReceiver), h.ruleSvc.Evaluate(e.As(rule.RoleReceiver))) {
+				h.abuseSvc.Withhold(ev.ItemUser, reaction, now)
+			}
+			if !h.credit(ev.User, ev.Item.Timestamp, e.As(rule.RoleGiver), h.ruleSvc.Evaluate(e.As(rule.RoleGiver))) {
+				h.abuseSvc.Withhold(ev.User, reaction, now)
 			}
 
 		case *slackevents.ReactionRemovedEvent:
@@ -139,11 +213,19 @@ func (h *EventHandler) events(c echo.Context) error {
 			}
 			h.logger.Info("received event", zap.String("user_id", ev.User), zap.String("event", "ReactionRemovedEvent"))
 
-			if err := h.queueSvc.Add(&model.User{ID: ev.ItemUser, Exp: -1, CreatedAt: time.Now()}); err != nil {
-				h.logger.Error("cannot add ReactionRemovedEvent to queue", zap.Error(err))
+			reaction := reactionKey(ev.User, ev.Item.Channel, ev.Item.Timestamp, ev.Reaction)
+			// both are asked so neither entry lingers when the ring check stops the removal
+			receiverWithheld := h.abuseSvc.Withheld(ev.ItemUser, reaction)
+			giverWithheld := h.abuseSvc.Withheld(ev.User, reaction)
+			if !h.abuseSvc.Reaction(ev.User, ev.ItemUser, false, now) {
+				break
+			}
+			e := rule.Event{Type: rule.EventReactionRemoved, ChannelID: ev.Item.Channel, Emoji: ev.Reaction, Time: now}
+			if !receiverWithheld {
+				h.credit(ev.ItemUser, ev.Item.Timestamp, e.As(rule.RoleReceiver), h.ruleSvc.Evaluate(e.As(rule.RoleReceiver)))
 			}
-			if err := h.queueSvc.Add(&model.User{ID: ev.User, Exp: -1, CreatedAt: time.Now()}); err != nil {
-				h.logger.Error("cannot add ReactionRemovedEvent to queue", zap.Error(err))
+			if !giverWithheld {
+				h.credit(ev.User, ev.Item.Timestamp, e.As(rule.RoleGiver), h.ruleSvc.Evaluate(e.As(rule.RoleGiver)))
 			}
 
 		}
@@ -152,3 +234,64 @@ func (h *EventHandler) events(c echo.Context) error {
 
 	return c.NoContent(http.StatusOK)
 }
+
+// credit queues the exp granted to userID, scaled by the channel setting, once it fits in the user's daily cap,
+// and reports whether any was queued
+func (h *EventHandler) credit(userID, messageTS string, e rule.Event, res rule.Result) bool {
+	rulePoints := res.Points
+	st := h.channelSetting(e.ChannelID)
+	if st.Excluded || (st.ReadOnly && e.Type == rule.EventMessage) {
+		return false
+	}
+	if st.Multiplier != 1 {
+		res.Points = int64(math.Round(float64(res.Points) * st.Multiplier))
+		res.Rules = append(res.Rules, fmt.Sprintf("channel_x%v", st.Multiplier))
+	}
+
+	points := h.abuseSvc.Cap(userID, res.Points, e.Time)
+	if points == 0 {
+		return false
+	}
+
+	if err := h.queueSvc.Add(&model.ExpEvent{
+		UserID:      userID,
+		EventType:   string(e.Type),
+		Role:        string(e.Role),
+		ChannelID:   e.ChannelID,
+		MessageTS:   messageTS,
+		ThreadReply: e.ThreadReply,
+		Emoji:       e.Emoji,
+		TextLength:  e.TextLength,
+		Points:      rulePoints,
+		Delta:       points,
+		Rule:        strings.Join(res.Rules, ","),
+		CreatedAt:   e.Time,
+	}); err != nil {
+		h.logger.Error("cannot add event to queue", zap.Error(err), zap.String("event", string(e.Type)))
+		return false
+	}
+	return true
+}
+
+// channelSetting falls back to the defaults when settings cannot be read, exp keeps flowing during db hiccups
+func (h *EventHandler) channelSetting(channelID string) model.ChannelSetting {
+	st, err := h.channelSvc.Get(channelID)
+	if err != nil {
+		h.logger.Error("cannot get channel setting", zap.Error(err), zap.String("channel_id", channelID))
+	}
+	return st
+}
+
+// reactionKey identifies a reaction by who left which emoji on which message
+func reactionKey(giverID, channelID, messageTS, emoji string) string {
+	return strings.Join([]string{giverID, channelID, messageTS, emoji}, ":")
+}
+
+// parseCommand splits a `$command args` message into the command and its arguments
+func parseCommand(text string) (string, string) {
+	parts := strings.SplitN(strings.TrimSpace(text), " ", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], strings.TrimSpace(parts[1])
+}
@@ -2,14 +2,20 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 
 	"github.com/labstack/echo"
 	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/badge"
 	"github.com/webuild-community/core/service/item"
+	"github.com/webuild-community/core/service/order"
 	"github.com/webuild-community/core/service/queue"
 	"github.com/webuild-community/core/service/user"
 	"go.uber.org/zap"
@@ -20,14 +26,18 @@ type InteractiveHandler struct {
 	queueSvc queue.Service
 	userSvc  user.Service
 	itemSvc  item.Service
+	badgeSvc badge.Service
+	orderSvc order.Service
 }
 
-func NewInteractiveHandler(e *echo.Echo, logger *zap.Logger, queueSvc queue.Service, userSvc user.Service, itemSvc item.Service) {
+func NewInteractiveHandler(e *echo.Echo, logger *zap.Logger, queueSvc queue.Service, userSvc user.Service, itemSvc item.Service, badgeSvc badge.Service, orderSvc order.Service) {
 	handler := &InteractiveHandler{
 		logger:   logger,
 		queueSvc: queueSvc,
 		userSvc:  userSvc,
 		itemSvc:  itemSvc,
+		badgeSvc: badgeSvc,
+		orderSvc: orderSvc,
 	}
 
 	e.POST("/slack/interactives", handler.interactives)
@@ -58,19 +68,112 @@ func (h *InteractiveHandler) interactives(c echo.Context) error {
 		return c.NoContent(http.StatusUnauthorized)
 	}
 
+	if message.Type == slack.InteractionTypeViewSubmission && message.View.CallbackID == order.ShippingCallbackID {
+		return h.shipping(c, slack.InteractionCallback(message))
+	}
+
 	if len(message.ActionCallback.BlockActions) == 0 {
 		return c.NoContent(http.StatusBadRequest)
 	}
 
-	switch message.ActionCallback.BlockActions[0].Text.Text {
+	action := message.ActionCallback.BlockActions[0]
+	if status, ok := order.Actions[action.ActionID]; ok {
+		return h.transition(c, slack.InteractionCallback(message), action, status)
+	}
+	if action.ActionID == order.ActionShipping {
+		orderID, _ := strconv.ParseUint(action.Value, 10, 64)
+		if err := h.orderSvc.OpenShippingForm(message.TriggerID, uint(orderID), message.User.ID); err != nil {
+			h.logger.Error("cannot open shipping form", zap.Error(err), zap.String("order_id", action.Value))
+			h.reply(message.ResponseURL, "Cannot open the shipping form, please try again later")
+		}
+		return c.NoContent(http.StatusOK)
+	}
+
+	switch action.Text.Text {
 	case "Redeem":
-		itemID := message.ActionCallback.BlockActions[0].Value
-		if err := h.itemSvc.Redeem(itemID, message.User.ID); err != nil {
+		itemID := action.Value
+		placed, err := h.itemSvc.Redeem(itemID, message.User.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, item.ErrInsufficientBalance):
+				h.reply(message.ResponseURL, "You do not have enough RDF to redeem this item")
+				return c.NoContent(http.StatusOK)
+			case errors.Is(err, item.ErrOutOfStock):
+				h.reply(message.ResponseURL, "Sorry, this item is sold out. Your RDF were not spent")
+				return c.NoContent(http.StatusOK)
+			}
 			h.logger.Error("cannot redeem item", zap.Error(err), zap.String("item_id", itemID), zap.String("user_id", message.User.ID))
+			h.reply(message.ResponseURL, "Cannot redeem this item, please try again later")
 			return c.NoContent(http.StatusInternalServerError)
 		}
+		h.reply(message.ResponseURL, fmt.Sprintf("Item redeemed successfully, order #%d is waiting for review", placed.ID))
+		if err := h.orderSvc.Placed(placed); err != nil {
+			h.logger.Error("cannot announce order", zap.Error(err), zap.Uint("order_id", placed.ID))
+		}
+		if _, err := h.badgeSvc.Evaluate(message.User.ID); err != nil {
+			h.logger.Error("cannot evaluate badges", zap.Error(err), zap.String("user_id", message.User.ID))
+		}
 		return c.NoContent(http.StatusOK)
 	}
 
 	return c.NoContent(http.StatusBadRequest)
 }
+
+func (h *InteractiveHandler) reply(responseURL, text string) {
+	if responseURL == "" {
+		return
+	}
+	if err := slack.PostWebhook(responseURL, &slack.WebhookMessage{Text: text}); err != nil {
+		h.logger.Error("cannot reply to interactive message", zap.Error(err))
+	}
+}
+
+// transition runs an admin action on an order
+func (h *InteractiveHandler) transition(c echo.Context, message slack.InteractionCallback, action *slack.BlockAction, status model.OrderStatus) error {
+	admin, err := h.userSvc.Find(message.User.ID)
+	if err != nil || !admin.IsAdmin {
+		h.reply(message.ResponseURL, "Only admins can handle orders")
+		return c.NoContent(http.StatusOK)
+	}
+
+	orderID, err := strconv.ParseUint(action.Value, 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	if _, err := h.orderSvc.Transition(uint(orderID), admin.ID, status); err != nil {
+		switch {
+		case errors.Is(err, order.ErrOrderNotFound):
+			h.reply(message.ResponseURL, "This order does not exist anymore")
+			return c.NoContent(http.StatusOK)
+		case errors.Is(err, order.ErrInvalidTransition):
+			h.reply(message.ResponseURL, fmt.Sprintf("Order #%d cannot be %s, it was already handled", orderID, status))
+			return c.NoContent(http.StatusOK)
+		}
+		h.logger.Error("cannot update order", zap.Error(err), zap.Uint64("order_id", orderID), zap.String("status", string(status)))
+		h.reply(message.ResponseURL, "Cannot update this order, please try again later")
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	h.reply(message.ResponseURL, fmt.Sprintf("Order #%d is now %s", orderID, status))
+	return c.NoContent(http.StatusOK)
+}
+
+// shipping saves the details submitted in the shipping modal
+func (h *InteractiveHandler) shipping(c echo.Context, message slack.InteractionCallback) error {
+	orderID, err := strconv.ParseUint(message.View.PrivateMetadata, 10, 64)
+	if err != nil || message.View.State == nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	details := message.View.State.Values[order.ShippingBlockID][order.ShippingActionID].Value
+
+	if err := h.orderSvc.SetShipping(uint(orderID), message.User.ID, details); err != nil {
+		if errors.Is(err, order.ErrInvalidTransition) {
+			return c.JSON(http.StatusOK, slack.NewErrorsViewSubmissionResponse(map[string]string{
+				order.ShippingBlockID: "This order was already handled, its shipping details cannot change",
+			}))
+		}
+		h.logger.Error("cannot save shipping details", zap.Error(err), zap.Uint64("order_id", orderID))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+type AbuseReason string
+
+const (
+	AbuseRateLimit    AbuseReason = "rate_limit"
+	AbuseDuplicate    AbuseReason = "duplicate_message"
+	AbuseDailyCap     AbuseReason = "daily_cap"
+	AbuseReactionRing AbuseReason = "reaction_ring"
+)
+
+// AbuseFlag records exp that was withheld from a user and reported to admins
+type AbuseFlag struct {
+	ID     uint        `gorm:"primarykey" json:"id"`
+	UserID string      `gorm:"size:20;not null;index" json:"user_id"`
+	Reason AbuseReason `gorm:"not null" json:"reason"`
+	Detail string      `json:"detail"`
+
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+}
+
+func (AbuseFlag) TableName() string {
+	return "abuse_flag"
+}
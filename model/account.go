@@ -0,0 +1,33 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+type AccountType string
+
+const (
+	AccountUser   AccountType = "user"
+	AccountSystem AccountType = "system"
+)
+
+// TreasuryAccountID is the system account RDF is minted from and spent back into
+const TreasuryAccountID = "system:treasury"
+
+type Account struct {
+	ID     string      `gorm:"size:64;primarykey" json:"id"`
+	Type   AccountType `gorm:"not null" json:"type"`
+	UserID string      `gorm:"size:20;index" json:"user_id"`
+
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+}
+
+func (Account) TableName() string {
+	return "account"
+}
+
+// UserAccountID returns the ledger account id owned by a slack user
+func UserAccountID(userID string) string {
+	return fmt.Sprintf("user:%s", userID)
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Badge is awarded once a user's Metric reaches Threshold
+type Badge struct {
+	Code        string `gorm:"size:64;primarykey" json:"code"`
+	Name        string `gorm:"not null" json:"name"`
+	Description string `json:"description"`
+	Emoji       string `json:"emoji"`
+	Metric      string `gorm:"not null" json:"metric"`
+	Threshold   int64  `gorm:"not null" json:"threshold"`
+
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:now()" json:"updated_at"`
+}
+
+func (Badge) TableName() string {
+	return "badge"
+}
+
+type UserBadge struct {
+	ID        uint   `gorm:"primarykey" json:"id"`
+	UserID    string `gorm:"size:20;not null;uniqueIndex:idx_user_badge" json:"user_id"`
+	BadgeCode string `gorm:"size:64;not null;uniqueIndex:idx_user_badge" json:"badge_code"`
+	Badge     Badge  `gorm:"foreignKey:BadgeCode" json:"badge"`
+
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+}
+
+func (UserBadge) TableName() string {
+	return "user_badge"
+}
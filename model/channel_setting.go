@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// ChannelSetting changes how exp is earned in one channel, channels without one use the defaults
+type ChannelSetting struct {
+	ChannelID  string  `gorm:"size:20;primarykey" json:"channel_id"`
+	// no default tag, gorm would swap a multiplier of 0 for it
+	Multiplier float64 `gorm:"not null" json:"multiplier"`
+	// Excluded channels grant no exp at all
+	Excluded bool `gorm:"default:false" json:"excluded"`
+	// ReadOnly announcement channels grant exp for reactions only
+	ReadOnly bool `gorm:"default:false" json:"read_only"`
+
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:now()" json:"updated_at"`
+}
+
+func (ChannelSetting) TableName() string {
+	return "channel_setting"
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// ExpEvent is one exp change, a user's exp is the sum of their events
+type ExpEvent struct {
+	ID        uint   `gorm:"primarykey" json:"id"`
+	UserID    string `gorm:"size:20;not null;index" json:"user_id"`
+	EventType string `gorm:"not null" json:"event_type"`
+	Role      string `json:"role"`
+	ChannelID string `gorm:"index" json:"channel_id"`
+	MessageTS string `json:"message_ts"`
+	Delta     int64  `gorm:"not null" json:"delta"`
+	Rule      string `json:"rule"`
+
+	// set on messages posted in a thread
+	ThreadReply bool `gorm:"default:false" json:"thread_reply"`
+
+	// the rule inputs, kept so recompute can rate the event again with the current rules
+	Emoji      string `json:"emoji"`
+	TextLength int    `gorm:"default:0" json:"text_length"`
+	Points     int64  `gorm:"default:0" json:"points"`
+	// Delta / Points once channel, cap and streak adjustments are applied, zero for unrated events
+	Scale float64 `gorm:"default:0" json:"scale"`
+
+	CreatedAt time.Time `gorm:"default:now();index" json:"created_at"`
+}
+
+func (ExpEvent) TableName() string {
+	return "exp_event"
+}
+
+const (
+	// ExpEventOpening is the event type of the balance carried over from before exp history existed
+	ExpEventOpening = "opening"
+	// ExpEventKudos is the event type of exp received through kudos
+	ExpEventKudos = "kudos"
+	// ExpEventQuest is the event type of exp rewarded for completing a quest
+	ExpEventQuest = "quest"
+)
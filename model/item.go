@@ -8,6 +8,12 @@ type Item struct {
 	Quantity uint    `gorm:"default:0" json:"quantity"`
 	Redeemed uint    `gorm:"default:0" json:"redeemed"`
 	Price    float64 `gorm:"default:0" json:"price"`
+
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	// expired items are hidden from the shop
+	ExpiresAt *time.Time `json:"expires_at"`
+
 	// Transactions []Transaction `json:"transactions"`
 	CreatedAt time.Time  `gorm:"default:now()" json:"created_at"`
 	UpdatedAt time.Time  `gorm:"default:now()" json:"updated_at"`
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+type ReasonCode string
+
+const (
+	ReasonOpeningBalance ReasonCode = "opening_balance"
+	ReasonAdjustment     ReasonCode = "adjustment"
+	ReasonRedeem         ReasonCode = "redeem"
+	ReasonReversal       ReasonCode = "reversal"
+	ReasonTip            ReasonCode = "tip"
+	ReasonLevelUp        ReasonCode = "level_up"
+	ReasonMilestone      ReasonCode = "milestone"
+	ReasonWeeklyTop      ReasonCode = "weekly_top"
+	ReasonSeasonReward   ReasonCode = "season_reward"
+	ReasonQuest          ReasonCode = "quest"
+)
+
+type PostingSide string
+
+const (
+	PostingDebit  PostingSide = "debit"
+	PostingCredit PostingSide = "credit"
+)
+
+// JournalEntry is an append-only ledger record, its postings always balance
+type JournalEntry struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	Reason         ReasonCode `gorm:"not null;index" json:"reason"`
+	IdempotencyKey string     `gorm:"not null;uniqueIndex" json:"idempotency_key"`
+	Memo           string     `json:"memo"`
+	ReversalOfID   *uint      `gorm:"uniqueIndex" json:"reversal_of_id"`
+	Postings       []Posting  `json:"postings"`
+
+	CreatedAt time.Time `gorm:"default:now();index" json:"created_at"`
+}
+
+func (JournalEntry) TableName() string {
+	return "journal_entry"
+}
+
+type Posting struct {
+	ID             uint        `gorm:"primarykey" json:"id"`
+	JournalEntryID uint        `gorm:"not null;index" json:"journal_entry_id"`
+	AccountID      string      `gorm:"size:64;not null;index" json:"account_id"`
+	Side           PostingSide `gorm:"not null" json:"side"`
+	Amount         float64     `gorm:"not null" json:"amount"`
+
+	CreatedAt time.Time `gorm:"default:now();index" json:"created_at"`
+}
+
+func (Posting) TableName() string {
+	return "posting"
+}
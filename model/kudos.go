@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Kudos is a shout-out from one member to another, the receiver earns exp for it
+type Kudos struct {
+	ID             uint   `gorm:"primarykey" json:"id"`
+	FromUserID     string `gorm:"size:20;not null;index" json:"from_user_id"`
+	ToUserID       string `gorm:"size:20;not null;index" json:"to_user_id"`
+	ChannelID      string `json:"channel_id"`
+	Reason         string `json:"reason"`
+	Exp            int64  `gorm:"not null" json:"exp"`
+	IdempotencyKey string `gorm:"uniqueIndex;not null" json:"idempotency_key"`
+
+	CreatedAt time.Time `gorm:"default:now();index" json:"created_at"`
+}
+
+func (Kudos) TableName() string {
+	return "kudos"
+}
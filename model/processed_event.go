@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// ProcessedEvent remembers a slack event id so retried deliveries are not handled twice
+type ProcessedEvent struct {
+	ID string `gorm:"size:64;primarykey" json:"id"`
+
+	CreatedAt time.Time `gorm:"default:now();index" json:"created_at"`
+}
+
+func (ProcessedEvent) TableName() string {
+	return "processed_event"
+}
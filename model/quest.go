@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// QuestGithubLinked is the event type of quests completed by linking a Github account
+const QuestGithubLinked = "github_linked"
+
+// Quest is completed once a user has Target exp events matching its criteria between StartsAt and EndsAt
+type Quest struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	Name        string `gorm:"not null" json:"name"`
+	Description string `json:"description"`
+
+	// criteria, empty fields match anything
+	EventType   string `gorm:"not null" json:"event_type"`
+	Role        string `json:"role"`
+	ChannelID   string `json:"channel_id"`
+	ThreadReply bool   `json:"thread_reply"`
+	Target      int64  `gorm:"not null;default:1" json:"target"`
+
+	RewardExp int64   `gorm:"default:0" json:"reward_exp"`
+	RewardRDF float64 `gorm:"default:0" json:"reward_rdf"`
+
+	StartsAt  time.Time  `gorm:"default:now()" json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	CreatedAt time.Time  `gorm:"default:now()" json:"created_at"`
+}
+
+func (Quest) TableName() string {
+	return "quest"
+}
+
+type QuestProgress struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	QuestID     uint       `gorm:"not null;uniqueIndex:idx_quest_progress" json:"quest_id"`
+	UserID      string     `gorm:"size:20;not null;uniqueIndex:idx_quest_progress" json:"user_id"`
+	Count       int64      `gorm:"not null;default:0" json:"count"`
+	CompletedAt *time.Time `json:"completed_at"`
+
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:now()" json:"updated_at"`
+}
+
+func (QuestProgress) TableName() string {
+	return "quest_progress"
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// QueueMessage is a pending job, it is hidden from other consumers while LockedUntil is in the future
+type QueueMessage struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	Payload     string     `gorm:"type:jsonb;not null" json:"payload"`
+	Attempts    int        `gorm:"default:0" json:"attempts"`
+	AvailableAt time.Time  `gorm:"default:now();index" json:"available_at"`
+	LockedUntil *time.Time `json:"locked_until"`
+	LastError   string     `json:"last_error"`
+
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+}
+
+func (QueueMessage) TableName() string {
+	return "queue_message"
+}
+
+// QueueDeadLetter keeps messages which failed too many times for manual inspection
+type QueueDeadLetter struct {
+	ID        uint   `gorm:"primarykey" json:"id"`
+	MessageID uint   `gorm:"index" json:"message_id"`
+	Payload   string `gorm:"type:jsonb;not null" json:"payload"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error"`
+
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+}
+
+func (QueueDeadLetter) TableName() string {
+	return "queue_dead_letter"
+}
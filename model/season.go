@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+type Season struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	Name      string     `gorm:"not null" json:"name"`
+	StartedAt time.Time  `gorm:"not null" json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+}
+
+func (Season) TableName() string {
+	return "season"
+}
+
+// SeasonStanding is a user's archived final position in an ended season
+type SeasonStanding struct {
+	ID       uint    `gorm:"primarykey" json:"id"`
+	SeasonID uint    `gorm:"not null;uniqueIndex:idx_season_standing" json:"season_id"`
+	UserID   string  `gorm:"size:20;not null;uniqueIndex:idx_season_standing" json:"user_id"`
+	Rank     int     `gorm:"not null" json:"rank"`
+	Exp      int64   `gorm:"not null" json:"exp"`
+	Reward   float64 `gorm:"default:0" json:"reward"`
+
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+}
+
+func (SeasonStanding) TableName() string {
+	return "season_standing"
+}
@@ -4,11 +4,32 @@ import (
 	"gorm.io/gorm"
 )
 
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderApproved  OrderStatus = "approved"
+	OrderFulfilled OrderStatus = "fulfilled"
+	OrderRejected  OrderStatus = "rejected"
+	OrderRefunded  OrderStatus = "refunded"
+)
+
+// Transaction is the order of an item, it is paid by its journal entry
 type Transaction struct {
 	gorm.Model
 	UserID string  `gorm:"not null" json:"user_id"`
 	ItemID string  `gorm:"not null" json:"item_id"`
 	Price  float64 `gorm:"not null" json:"quantity"`
+
+	JournalEntryID *uint `json:"journal_entry_id"`
+
+	// fulfilment
+	Status          OrderStatus `gorm:"not null;default:pending;index" json:"status"`
+	ShippingDetails string      `json:"shipping_details"`
+	HandledBy       string      `json:"handled_by"`
+	// the order message admins act on
+	FulfilmentChannelID string `json:"fulfilment_channel_id"`
+	FulfilmentTS        string `json:"fulfilment_ts"`
 }
 
 func (Transaction) TableName() string {
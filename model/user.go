@@ -7,7 +7,16 @@ type User struct {
 	IsAdmin bool    `gorm:"default:false" json:"is_admin"`
 	Exp     int64   `gorm:"default:0" json:"exp"`
 	Level   uint    `gorm:"default:1" json:"level"`
-	Balance float64 `gorm:"default:0" json:"balance"`
+	Balance float64 `gorm:"default:0" json:"balance"` // cache of the user ledger account, only written by the ledger
+
+	// activity streak, dates are in the user's TZ
+	StreakDays     int    `gorm:"default:0" json:"streak_days"`
+	LongestStreak  int    `gorm:"default:0" json:"longest_streak"`
+	LastActiveDate string `gorm:"size:10" json:"last_active_date"`
+
+	// ranking score follows exp but decays while the user is inactive
+	RankingScore int64     `gorm:"default:0" json:"ranking_score"`
+	LastActiveAt time.Time `gorm:"default:now()" json:"last_active_at"`
 
 	// Github info
 	GithubUsername string `json:"github_username"`
@@ -21,10 +30,17 @@ type User struct {
 	TZ            string `json:"tz"`
 	ImageOriginal string `json:"image_original"`
 	SlackEmail    string `json:"slack_email"`
+	IsBot         bool   `gorm:"default:false" json:"is_bot"`
+
+	ProfileSyncedAt *time.Time `json:"profile_synced_at"`
 
 	WalletAddress string        `json:"wallet_address"`
 	Transactions  []Transaction `json:"transactions"`
 
+	// notification settings
+	NotificationsOptOut bool       `gorm:"default:false" json:"notifications_opt_out"`
+	LevelNotifiedAt     *time.Time `json:"level_notified_at"`
+
 	// only be used for temporary storing data
 	SlackChannel string `json:"slack_channel"`
 
@@ -39,7 +55,3 @@ type User struct {
 func (User) TableName() string {
 	return "user"
 }
-
-func (o User) IsLevelUp() bool {
-	return o.Exp >= int64(o.Level)*100
-}
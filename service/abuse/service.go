@@ -0,0 +1,80 @@
+package abuse
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Limits configures the detectors, a zero value disables the matching check
+type Limits struct {
+	// at most RateLimit messages per user and channel earn exp within RateWindow
+	RateLimit  int
+	RateWindow time.Duration
+	// a message whose words overlap a recent one by at least DuplicateSimilarity earns nothing,
+	// messages with fewer than DuplicateMinWords distinct words are not compared
+	DuplicateHistory    int
+	DuplicateSimilarity float64
+	DuplicateMinWords   int
+	// positive exp a user can earn per day
+	DailyCap int64
+	// users reacting to each other at least RingThreshold times within RingWindow form a ring
+	RingThreshold int
+	RingWindow    time.Duration
+}
+
+// NewLimitsFromEnv reads limits from ABUSE_* environment variables. The detectors count in
+// memory: a restart starts every user over, and each replica keeps its own counts, so n
+// replicas let a user earn up to n times the daily cap
+func NewLimitsFromEnv() Limits {
+	l := Limits{
+		RateLimit:           10,
+		RateWindow:          time.Minute,
+		DuplicateHistory:    10,
+		DuplicateSimilarity: 0.9,
+		DuplicateMinWords:   3,
+		DailyCap:            500,
+		RingThreshold:       20,
+		RingWindow:          24 * time.Hour,
+	}
+	if v, err := strconv.Atoi(os.Getenv("ABUSE_RATE_LIMIT")); err == nil {
+		l.RateLimit = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("ABUSE_RATE_WINDOW")); err == nil {
+		l.RateWindow = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("ABUSE_DUPLICATE_SIMILARITY"), 64); err == nil {
+		l.DuplicateSimilarity = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("ABUSE_DUPLICATE_MIN_WORDS")); err == nil {
+		l.DuplicateMinWords = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("ABUSE_DAILY_CAP"), 10, 64); err == nil {
+		l.DailyCap = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("ABUSE_RING_THRESHOLD")); err == nil {
+		l.RingThreshold = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("ABUSE_RING_WINDOW")); err == nil {
+		l.RingWindow = v
+	}
+	return l
+}
+
+type Service interface {
+	// Message returns the points a message may earn after rate and duplicate checks
+	Message(userID, channelID, text string, points int64, at time.Time) int64
+	// Reaction reports whether a reaction between two users may earn exp
+	Reaction(giverID, receiverID string, added bool, at time.Time) bool
+	// Cap trims positive points to what is left of the user's daily ceiling
+	Cap(userID string, points int64, at time.Time) int64
+	// Refund gives back points Cap let through that were never credited
+	Refund(userID string, points int64, at time.Time)
+	// Withhold remembers a reaction whose exp the user did not get, see Withheld
+	Withhold(userID, reaction string, at time.Time)
+	// Withheld reports and forgets whether the user's exp for a reaction was withheld, its
+	// removal must not take back exp that was never given
+	Withheld(userID, reaction string) bool
+	// Prune drops tracking state that is too old to matter
+	Prune(now time.Time)
+}
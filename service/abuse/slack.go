@@ -0,0 +1,277 @@
+package abuse
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/slackutil"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// withheldTTL is how long a withheld reaction is remembered for its removal
+const withheldTTL = 7 * 24 * time.Hour
+
+// slackSvc keeps detector state in memory, it is reset on restart which only ever
+// errs on the side of crediting users
+type slackSvc struct {
+	logger        *zap.Logger
+	db            *gorm.DB
+	slackClient   *slack.Client
+	limits        Limits
+	reportChannel string
+
+	mu        sync.Mutex
+	messages  map[string][]time.Time       // user:channel -> credited message times
+	history   map[string][]map[string]bool // user -> word sets of recent messages
+	daily     map[string]int64             // user:date -> positive exp earned
+	reactions map[string][]time.Time       // giver:receiver -> reaction times
+	withheld  map[string]time.Time         // user:reaction -> time its exp was withheld
+	reported  map[string]bool              // user:reason:date -> already reported
+}
+
+// NewSlackService reports flags to reportChannel, or to every admin by DM when it is empty
+func NewSlackService(logger *zap.Logger, db *gorm.DB, slackClient *slack.Client, limits Limits, reportChannel string) Service {
+	return &slackSvc{
+		logger:        logger,
+		db:            db,
+		slackClient:   slackClient,
+		limits:        limits,
+		reportChannel: reportChannel,
+		messages:      map[string][]time.Time{},
+		history:       map[string][]map[string]bool{},
+		daily:         map[string]int64{},
+		reactions:     map[string][]time.Time{},
+		withheld:      map[string]time.Time{},
+		reported:      map[string]bool{},
+	}
+}
+
+func (s *slackSvc) Message(userID, channelID, text string, points int64, at time.Time) int64 {
+	if points <= 0 {
+		return points
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.limits.RateLimit > 0 {
+		key := userID + ":" + channelID
+		recent := since(s.messages[key], at.Add(-s.limits.RateWindow))
+		if len(recent) >= s.limits.RateLimit {
+			s.messages[key] = recent
+			s.flag(userID, model.AbuseRateLimit, at, fmt.Sprintf("more than %d messages within %v in <#%s>", s.limits.RateLimit, s.limits.RateWindow, channelID))
+			return 0
+		}
+		s.messages[key] = append(recent, at)
+	}
+
+	words := wordSet(text)
+	// short replies like "thanks" repeat naturally, only the rate limit applies to them
+	if s.limits.DuplicateHistory > 0 && s.limits.DuplicateSimilarity > 0 && len(words) >= s.limits.DuplicateMinWords {
+		for _, prev := range s.history[userID] {
+			if similarity(words, prev) >= s.limits.DuplicateSimilarity {
+				s.flag(userID, model.AbuseDuplicate, at, fmt.Sprintf("repeated message in <#%s>", channelID))
+				return 0
+			}
+		}
+
+		h := append(s.history[userID], words)
+		if len(h) > s.limits.DuplicateHistory {
+			h = h[len(h)-s.limits.DuplicateHistory:]
+		}
+		s.history[userID] = h
+	}
+
+	return points
+}
+
+func (s *slackSvc) Reaction(giverID, receiverID string, added bool, at time.Time) bool {
+	if s.limits.RingThreshold <= 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from := since(s.reactions[giverID+":"+receiverID], at.Add(-s.limits.RingWindow))
+	if added {
+		from = append(from, at)
+	}
+	s.reactions[giverID+":"+receiverID] = from
+
+	back := since(s.reactions[receiverID+":"+giverID], at.Add(-s.limits.RingWindow))
+	s.reactions[receiverID+":"+giverID] = back
+
+	if len(from) < s.limits.RingThreshold || len(back) < s.limits.RingThreshold {
+		return true
+	}
+
+	detail := fmt.Sprintf("<@%s> and <@%s> exchanged %d reactions within %v", giverID, receiverID, len(from)+len(back), s.limits.RingWindow)
+	s.flag(giverID, model.AbuseReactionRing, at, detail)
+	s.flag(receiverID, model.AbuseReactionRing, at, detail)
+	return false
+}
+
+func (s *slackSvc) Cap(userID string, points int64, at time.Time) int64 {
+	if points <= 0 || s.limits.DailyCap <= 0 {
+		return points
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := userID + ":" + at.Format("2006-01-02")
+	left := s.limits.DailyCap - s.daily[key]
+	if left <= 0 {
+		s.flag(userID, model.AbuseDailyCap, at, fmt.Sprintf("reached the daily cap of %d exp", s.limits.DailyCap))
+		return 0
+	}
+	if points > left {
+		points = left
+	}
+	s.daily[key] += points
+	return points
+}
+
+func (s *slackSvc) Refund(userID string, points int64, at time.Time) {
+	if points <= 0 || s.limits.DailyCap <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := userID + ":" + at.Format("2006-01-02")
+	if s.daily[key] -= points; s.daily[key] <= 0 {
+		delete(s.daily, key)
+	}
+}
+
+func (s *slackSvc) Withhold(userID, reaction string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.withheld[userID+":"+reaction] = at
+}
+
+func (s *slackSvc) Withheld(userID, reaction string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := userID + ":" + reaction
+	_, ok := s.withheld[key]
+	delete(s.withheld, key)
+	return ok
+}
+
+func (s *slackSvc) Prune(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, v := range s.messages {
+		if v = since(v, now.Add(-s.limits.RateWindow)); len(v) == 0 {
+			delete(s.messages, k)
+		} else {
+			s.messages[k] = v
+		}
+	}
+	for k, v := range s.reactions {
+		if v = since(v, now.Add(-s.limits.RingWindow)); len(v) == 0 {
+			delete(s.reactions, k)
+		} else {
+			s.reactions[k] = v
+		}
+	}
+
+	for k, at := range s.withheld {
+		if at.Before(now.Add(-withheldTTL)) {
+			delete(s.withheld, k)
+		}
+	}
+
+	today := now.Format("2006-01-02")
+	for k := range s.daily {
+		if !strings.HasSuffix(k, today) {
+			delete(s.daily, k)
+		}
+	}
+	for k := range s.reported {
+		if !strings.HasSuffix(k, today) {
+			delete(s.reported, k)
+		}
+	}
+}
+
+// flag records and reports a user at most once per reason and day, callers hold s.mu
+func (s *slackSvc) flag(userID string, reason model.AbuseReason, at time.Time, detail string) {
+	key := fmt.Sprintf("%s:%s:%s", userID, reason, at.Format("2006-01-02"))
+	if s.reported[key] {
+		return
+	}
+	s.reported[key] = true
+
+	go s.report(model.AbuseFlag{UserID: userID, Reason: reason, Detail: detail})
+}
+
+func (s *slackSvc) report(f model.AbuseFlag) {
+	if err := s.db.Create(&f).Error; err != nil {
+		s.logger.Error("cannot save abuse flag", zap.Error(err), zap.String("user_id", f.UserID))
+	}
+
+	text := fmt.Sprintf("*Exp withheld*\n<@%s> was flagged for `%s`: %s", f.UserID, f.Reason, f.Detail)
+	if s.reportChannel != "" {
+		if _, _, err := s.slackClient.PostMessage(s.reportChannel, slack.MsgOptionText(text, false)); err != nil {
+			s.logger.Error("cannot report abuse flag", zap.Error(err))
+		}
+		return
+	}
+
+	admins := []model.User{}
+	if err := s.db.Where("is_admin = ?", true).Find(&admins).Error; err != nil {
+		s.logger.Error("cannot find admins", zap.Error(err))
+		return
+	}
+	for _, a := range admins {
+		slackutil.DM(s.slackClient, s.logger, a.ID, slack.MsgOptionText(text, false))
+	}
+}
+
+// since drops the times before t, times are appended in order
+func since(times []time.Time, t time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(t) {
+		i++
+	}
+	return times[i:]
+}
+
+func wordSet(text string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// similarity is the Jaccard index of two word sets
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for w := range a {
+		if b[w] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
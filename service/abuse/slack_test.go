@@ -0,0 +1,48 @@
+package abuse
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func newTestService(limits Limits) Service {
+	return NewSlackService(zap.NewNop(), nil, nil, limits, "")
+}
+
+func TestMessageShortRepliesAreNotDuplicates(t *testing.T) {
+	s := newTestService(Limits{DuplicateHistory: 10, DuplicateSimilarity: 0.9, DuplicateMinWords: 3})
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if got := s.Message("U1", "C1", "thanks!", 5, now); got != 5 {
+			t.Fatalf("reply %d earned %d, want 5", i+1, got)
+		}
+	}
+}
+
+func TestRefundGivesCapBack(t *testing.T) {
+	s := newTestService(Limits{DailyCap: 10})
+	now := time.Now()
+	if got := s.Cap("U1", 8, now); got != 8 {
+		t.Fatalf("Cap(8) = %d, want 8", got)
+	}
+	s.Refund("U1", 3, now)
+	if got := s.Cap("U1", 10, now); got != 5 {
+		t.Fatalf("Cap(10) after a refund of 3 = %d, want 5", got)
+	}
+}
+
+func TestWithheldIsForgottenOnce(t *testing.T) {
+	s := newTestService(Limits{})
+	s.Withhold("U1", "U2:C1:1.0:tada", time.Now())
+	if s.Withheld("U2", "U2:C1:1.0:tada") {
+		t.Fatal("Withheld reports a reaction withheld from another user")
+	}
+	if !s.Withheld("U1", "U2:C1:1.0:tada") {
+		t.Fatal("Withheld misses a withheld reaction")
+	}
+	if s.Withheld("U1", "U2:C1:1.0:tada") {
+		t.Fatal("Withheld reports the same reaction twice")
+	}
+}
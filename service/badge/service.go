@@ -0,0 +1,52 @@
+package badge
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/webuild-community/core/model"
+)
+
+// metrics a badge can be awarded on
+const (
+	MetricMessages          = "messages"
+	MetricReactionsReceived = "reactions_received"
+	MetricStreakDays        = "streak_days"
+	MetricRedeems           = "redeems"
+	MetricGithubLinked      = "github_linked"
+)
+
+// DefaultBadges are used when no badge file is configured
+var DefaultBadges = []model.Badge{
+	{Code: "first_message", Name: "Hello World", Description: "Sent a first message", Emoji: ":wave:", Metric: MetricMessages, Threshold: 1},
+	{Code: "reactions_100", Name: "Crowd Pleaser", Description: "Received 100 reactions", Emoji: ":star2:", Metric: MetricReactionsReceived, Threshold: 100},
+	{Code: "streak_7", Name: "On Fire", Description: "Active 7 days in a row", Emoji: ":fire:", Metric: MetricStreakDays, Threshold: 7},
+	{Code: "first_redeem", Name: "Shopper", Description: "Redeemed a first item", Emoji: ":shopping_bags:", Metric: MetricRedeems, Threshold: 1},
+	{Code: "github_linked", Name: "Octocat", Description: "Linked a Github account", Emoji: ":octocat:", Metric: MetricGithubLinked, Threshold: 1},
+}
+
+// LoadBadges reads badge definitions from a JSON file, DefaultBadges are returned when path is empty
+func LoadBadges(path string) ([]model.Badge, error) {
+	if path == "" {
+		return DefaultBadges, nil
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	badges := []model.Badge{}
+	if err := json.Unmarshal(buf, &badges); err != nil {
+		return nil, err
+	}
+	return badges, nil
+}
+
+type Service interface {
+	// Sync stores the badge definitions
+	Sync() error
+	// Evaluate awards the badges userID newly qualifies for and returns them
+	Evaluate(userID string) ([]model.Badge, error)
+	List(userID string) ([]model.Badge, error)
+}
@@ -0,0 +1,124 @@
+package badge
+
+import (
+	"fmt"
+
+	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/rule"
+	"github.com/webuild-community/core/service/slackutil"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type slackSvc struct {
+	logger      *zap.Logger
+	db          *gorm.DB
+	slackClient *slack.Client
+	badges      []model.Badge
+}
+
+// NewSlackService --
+func NewSlackService(logger *zap.Logger, db *gorm.DB, slackClient *slack.Client, badges []model.Badge) Service {
+	return &slackSvc{
+		logger:      logger,
+		db:          db,
+		slackClient: slackClient,
+		badges:      badges,
+	}
+}
+
+func (s *slackSvc) Sync() error {
+	if len(s.badges) == 0 {
+		return nil
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "description", "emoji", "metric", "threshold", "updated_at"}),
+	}).Create(&s.badges).Error
+}
+
+func (s *slackSvc) Evaluate(userID string) ([]model.Badge, error) {
+	pending := []model.Badge{}
+	if err := s.db.
+		Where("code NOT IN (?)", s.db.Model(&model.UserBadge{}).Select("badge_code").Where("user_id = ?", userID)).
+		Find(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	values := map[string]int64{}
+	awarded := []model.Badge{}
+	for _, b := range pending {
+		v, ok := values[b.Metric]
+		if !ok {
+			var err error
+			if v, err = s.metric(b.Metric, userID); err != nil {
+				return nil, err
+			}
+			values[b.Metric] = v
+		}
+		if v < b.Threshold {
+			continue
+		}
+
+		res := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.UserBadge{UserID: userID, BadgeCode: b.Code})
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		awarded = append(awarded, b)
+
+		slackutil.DM(s.slackClient, s.logger, userID, slack.MsgOptionText(
+			fmt.Sprintf("*New badge* %s\nYou earned *%s*: %s", b.Emoji, b.Name, b.Description),
+			false,
+		))
+	}
+
+	return awarded, nil
+}
+
+func (s *slackSvc) List(userID string) ([]model.Badge, error) {
+	badges := []model.Badge{}
+	err := s.db.
+		Joins("JOIN user_badge ON user_badge.badge_code = badge.code").
+		Where("user_badge.user_id = ?", userID).
+		Order("user_badge.created_at").
+		Find(&badges).Error
+	return badges, err
+}
+
+func (s *slackSvc) metric(name, userID string) (int64, error) {
+	var v int64
+	var err error
+	switch name {
+	case MetricMessages:
+		err = s.db.Model(&model.ExpEvent{}).
+			Where("user_id = ? AND event_type = ?", userID, rule.EventMessage).
+			Count(&v).Error
+
+	case MetricReactionsReceived:
+		err = s.db.Model(&model.ExpEvent{}).
+			Where("user_id = ? AND event_type = ? AND role = ?", userID, rule.EventReactionAdded, rule.RoleReceiver).
+			Count(&v).Error
+
+	case MetricStreakDays:
+		// streaks are tracked in the user's TZ by the streak service
+		err = s.db.Model(&model.User{}).Select("longest_streak").Where("id = ?", userID).Scan(&v).Error
+
+	case MetricRedeems:
+		// rejected and refunded orders gave their RDF back, they were never redeemed
+		err = s.db.Model(&model.Transaction{}).
+			Where("user_id = ? AND status IN ?", userID, []model.OrderStatus{model.OrderPending, model.OrderApproved, model.OrderFulfilled}).
+			Count(&v).Error
+
+	case MetricGithubLinked:
+		err = s.db.Model(&model.User{}).Where("id = ? AND github_username <> ''", userID).Count(&v).Error
+
+	default:
+		err = fmt.Errorf("unknown badge metric %q", name)
+	}
+	return v, err
+}
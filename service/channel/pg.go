@@ -0,0 +1,86 @@
+package channel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/webuild-community/core/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// settings are read on every slack event, they are cached for cacheTTL
+const cacheTTL = time.Minute
+
+type pg struct {
+	db *gorm.DB
+
+	mu       sync.RWMutex
+	cache    map[string]model.ChannelSetting
+	loadedAt time.Time
+}
+
+// NewPGService --
+func NewPGService(db *gorm.DB) Service {
+	return &pg{db: db}
+}
+
+func (s *pg) Get(channelID string) (model.ChannelSetting, error) {
+	s.mu.RLock()
+	fresh := s.cache != nil && time.Since(s.loadedAt) < cacheTTL
+	setting, ok := s.cache[channelID]
+	s.mu.RUnlock()
+
+	if !fresh {
+		if err := s.load(); err != nil {
+			return Default(channelID), err
+		}
+		s.mu.RLock()
+		setting, ok = s.cache[channelID]
+		s.mu.RUnlock()
+	}
+	if !ok {
+		return Default(channelID), nil
+	}
+	return setting, nil
+}
+
+func (s *pg) List() ([]model.ChannelSetting, error) {
+	settings := []model.ChannelSetting{}
+	return settings, s.db.Order("channel_id").Find(&settings).Error
+}
+
+func (s *pg) Save(setting model.ChannelSetting) error {
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"multiplier", "excluded", "read_only", "updated_at"}),
+	}).Create(&setting).Error; err != nil {
+		return err
+	}
+	return s.load()
+}
+
+func (s *pg) Reset(channelID string) error {
+	if err := s.db.Delete(&model.ChannelSetting{}, "channel_id = ?", channelID).Error; err != nil {
+		return err
+	}
+	return s.load()
+}
+
+func (s *pg) load() error {
+	settings, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	cache := make(map[string]model.ChannelSetting, len(settings))
+	for _, st := range settings {
+		cache[st.ChannelID] = st
+	}
+
+	s.mu.Lock()
+	s.cache = cache
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
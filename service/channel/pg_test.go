@@ -0,0 +1,38 @@
+package channel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/webuild-community/core/model"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestSaveKeepsZeroMultiplier(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sql string
+	var vars []interface{}
+	db.Callback().Create().After("gorm:create").Register("test:capture", func(tx *gorm.DB) {
+		sql, vars = tx.Statement.SQL.String(), tx.Statement.Vars
+	})
+
+	if err := NewPGService(db).Save(model.ChannelSetting{ChannelID: "C0123", Multiplier: 0}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if !strings.Contains(sql, `"multiplier"`) {
+		t.Fatalf("multiplier is left to the column default: %s", sql)
+	}
+	found := false
+	for _, v := range vars {
+		if f, ok := v.(float64); ok && f == 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("multiplier 0 is not written, vars = %v", vars)
+	}
+}
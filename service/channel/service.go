@@ -0,0 +1,19 @@
+package channel
+
+import (
+	"github.com/webuild-community/core/model"
+)
+
+type Service interface {
+	// Get returns the setting of a channel, or the defaults when it has none
+	Get(channelID string) (model.ChannelSetting, error)
+	List() ([]model.ChannelSetting, error)
+	Save(setting model.ChannelSetting) error
+	// Reset drops a channel's setting so it uses the defaults again
+	Reset(channelID string) error
+}
+
+// Default is the setting of channels that were never configured
+func Default(channelID string) model.ChannelSetting {
+	return model.ChannelSetting{ChannelID: channelID, Multiplier: 1}
+}
@@ -1,8 +1,14 @@
 package command
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+)
+
+var ErrNoItemSource = errors.New("no item source to sync from")
 
 type Service interface {
 	Verify(r *http.Request) (interface{}, error)
-	Sync() error
+	// Sync imports the item catalog and returns how many items were imported
+	Sync() (int, error)
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/service/item"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -13,20 +14,25 @@ type slackSvc struct {
 	logger *zap.Logger
 	db     *gorm.DB
 	client *slack.Client
+	syncer item.Syncer
 }
 
 // NewSlackService --
-func NewSlackService(logger *zap.Logger, db *gorm.DB, client *slack.Client) Service {
+func NewSlackService(logger *zap.Logger, db *gorm.DB, client *slack.Client, syncer item.Syncer) Service {
 	return &slackSvc{
 		logger: logger,
 		db:     db,
 		client: client,
+		syncer: syncer,
 	}
 }
 
-func (s *slackSvc) Sync() error {
+func (s *slackSvc) Sync() (int, error) {
 	s.logger.Info("handling sync")
-	return nil
+	if s.syncer == nil {
+		return 0, ErrNoItemSource
+	}
+	return s.syncer.Import()
 }
 
 func (s *slackSvc) Verify(r *http.Request) (interface{}, error) {
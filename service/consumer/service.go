@@ -0,0 +1,6 @@
+package consumer
+
+type Service interface {
+	// Consume drains the queue batch by batch until it is empty
+	Consume() error
+}
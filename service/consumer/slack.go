@@ -0,0 +1,288 @@
+package consumer
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/abuse"
+	"github.com/webuild-community/core/service/badge"
+	"github.com/webuild-community/core/service/mint"
+	"github.com/webuild-community/core/service/notification"
+	"github.com/webuild-community/core/service/quest"
+	"github.com/webuild-community/core/service/queue"
+	"github.com/webuild-community/core/service/rule"
+	"github.com/webuild-community/core/service/streak"
+	"github.com/webuild-community/core/service/user"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	metrics            = expvar.NewMap("consumer")
+	lastBatchSize      = new(expvar.Int)
+	lastBatchLatencyMS = new(expvar.Int)
+)
+
+func init() {
+	metrics.Set("last_batch_size", lastBatchSize)
+	metrics.Set("last_batch_latency_ms", lastBatchLatencyMS)
+}
+
+type slackSvc struct {
+	logger      *zap.Logger
+	db          *gorm.DB
+	slackClient *slack.Client
+	queueSvc    queue.Service
+	userSvc     user.Service
+	mintSvc     mint.Service
+	notifySvc   notification.Service
+	badgeSvc    badge.Service
+	streakSvc   streak.Service
+	questSvc    quest.Service
+	abuseSvc    abuse.Service
+	batchSize   int
+	profileTTL  time.Duration
+}
+
+// NewSlackService --
+func NewSlackService(
+	logger *zap.Logger,
+	db *gorm.DB,
+	slackClient *slack.Client,
+	queueSvc queue.Service,
+	userSvc user.Service,
+	mintSvc mint.Service,
+	notifySvc notification.Service,
+	badgeSvc badge.Service,
+	streakSvc streak.Service,
+	questSvc quest.Service,
+	abuseSvc abuse.Service,
+	batchSize int,
+	profileTTL time.Duration,
+) Service {
+	return &slackSvc{
+		logger:      logger,
+		db:          db,
+		slackClient: slackClient,
+		queueSvc:    queueSvc,
+		userSvc:     userSvc,
+		mintSvc:     mintSvc,
+		notifySvc:   notifySvc,
+		badgeSvc:    badgeSvc,
+		streakSvc:   streakSvc,
+		questSvc:    questSvc,
+		abuseSvc:    abuseSvc,
+		batchSize:   batchSize,
+		profileTTL:  profileTTL,
+	}
+}
+
+// pending is the coalesced state of every queued exp event of one user
+type pending struct {
+	user       model.User
+	events     []model.ExpEvent
+	tracked    []model.ExpEvent // events with streak bonuses, set once the streak is tracked
+	quests     []model.Quest
+	channelID  string
+	messageIDs []uint
+}
+
+func (s *slackSvc) Consume() error {
+	for {
+		msgs, err := s.queueSvc.Claim(s.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			return nil
+		}
+
+		start := time.Now()
+		s.consumeBatch(msgs)
+
+		elapsed := time.Since(start)
+		metrics.Add("batches", 1)
+		metrics.Add("messages", int64(len(msgs)))
+		lastBatchSize.Set(int64(len(msgs)))
+		lastBatchLatencyMS.Set(elapsed.Milliseconds())
+		s.logger.Info("consumed batch", zap.Int("size", len(msgs)), zap.Duration("latency", elapsed))
+	}
+}
+
+func (s *slackSvc) consumeBatch(msgs []queue.Message) {
+	batch := map[string]*pending{}
+	for _, m := range msgs {
+		e, ok := m.Value.(*model.ExpEvent)
+		if !ok {
+			s.queueSvc.Ack(m.ID)
+			continue
+		}
+
+		p, ok := batch[e.UserID]
+		if !ok {
+			p = &pending{}
+			batch[e.UserID] = p
+		}
+		p.events = append(p.events, *e)
+		p.messageIDs = append(p.messageIDs, m.ID)
+		// level changes are announced where the user last wrote
+		if e.EventType == string(rule.EventMessage) {
+			p.channelID = e.ChannelID
+		}
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(batch))
+	for id := range batch {
+		ids = append(ids, id)
+	}
+	users, err := s.userSvc.FindMany(ids)
+	if err != nil {
+		s.logger.Error("cannot find users", zap.Error(err))
+		s.failAll(batch, err)
+		return
+	}
+
+	for id, p := range batch {
+		u, ok := users[id]
+		if !ok || u.ProfileSyncedAt == nil || time.Since(*u.ProfileSyncedAt) > s.profileTTL {
+			if u, err = s.syncProfile(id); err != nil {
+				s.logger.Error("cannot sync slack profile", zap.Error(err), zap.String("user_id", id))
+				s.fail(p, err)
+				delete(batch, id)
+				continue
+			}
+		}
+		if u.IsBot {
+			s.ack(p)
+			delete(batch, id)
+			continue
+		}
+		p.user = u
+	}
+
+	// streaks, quest progress, exp and acks commit together, a retried message cannot count twice
+	var changes []user.ExpChange
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		events := []model.ExpEvent{}
+		for id, p := range batch {
+			// a savepoint per user keeps one bad user from failing the whole batch
+			if err := tx.Transaction(func(tx *gorm.DB) error {
+				return s.track(tx, p)
+			}); err != nil {
+				s.logger.Error("cannot track streak and quests", zap.Error(err), zap.String("user_id", id))
+				s.refund(p)
+				s.fail(p, err)
+				delete(batch, id)
+				continue
+			}
+			events = append(events, p.tracked...)
+		}
+
+		var err error
+		if changes, err = s.userSvc.WithTx(tx).AddExp(events); err != nil {
+			return err
+		}
+		queueSvc := s.queueSvc.WithTx(tx)
+		for _, p := range batch {
+			for _, id := range p.messageIDs {
+				if err := queueSvc.Ack(id); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("cannot update user exp", zap.Error(err))
+		for _, p := range batch {
+			s.refund(p)
+		}
+		s.failAll(batch, err)
+		return
+	}
+
+	for id, p := range batch {
+		s.questSvc.Announce(id, p.quests)
+	}
+	for _, c := range changes {
+		if err := s.mintSvc.OnExpChange(c.UserID, c.OldExp, c.NewExp, c.OldLevel, c.NewLevel, batch[c.UserID].channelID); err != nil {
+			s.logger.Error("cannot mint rewards", zap.Error(err), zap.String("user_id", c.UserID))
+		}
+		if err := s.notifySvc.LevelChanged(c.UserID, c.OldLevel, c.NewLevel, batch[c.UserID].channelID); err != nil {
+			s.logger.Error("cannot notify level change", zap.Error(err), zap.String("user_id", c.UserID))
+		}
+		if _, err := s.badgeSvc.Evaluate(c.UserID); err != nil {
+			s.logger.Error("cannot evaluate badges", zap.Error(err), zap.String("user_id", c.UserID))
+		}
+	}
+}
+
+// track applies the user's streak to their events and advances their quests within tx
+func (s *slackSvc) track(tx *gorm.DB, p *pending) error {
+	p.tracked = nil
+	events, err := s.streakSvc.WithTx(tx).Track(p.user, p.events)
+	if err != nil {
+		return err
+	}
+	p.tracked = events
+	p.quests, err = s.questSvc.WithTx(tx).Track(p.user.ID, events)
+	return err
+}
+
+// refund gives the daily cap back the streak bonuses of events that were not credited
+func (s *slackSvc) refund(p *pending) {
+	for i, e := range p.tracked {
+		s.abuseSvc.Refund(e.UserID, e.Delta-p.events[i].Delta, e.CreatedAt)
+	}
+}
+
+func (s *slackSvc) syncProfile(userID string) (model.User, error) {
+	sUser, err := s.slackClient.GetUserInfo(userID)
+	if err != nil {
+		return model.User{}, err
+	}
+	metrics.Add("profile_syncs", 1)
+	// stay below the users.info rate limit when many profiles expire at once
+	time.Sleep(100 * time.Millisecond)
+
+	u, err := s.userSvc.Update(userID, map[string]interface{}{
+		"first_name":        sUser.Profile.FirstName,
+		"last_name":         sUser.Profile.LastName,
+		"real_name":         sUser.Profile.RealName,
+		"display_name":      sUser.Profile.DisplayName,
+		"tz":                sUser.TZ,
+		"image_original":    sUser.Profile.ImageOriginal,
+		"slack_email":       sUser.Profile.Email,
+		"is_bot":            sUser.IsBot,
+		"profile_synced_at": time.Now(),
+	})
+	u.IsBot = sUser.IsBot
+	return u, err
+}
+
+func (s *slackSvc) ack(p *pending) {
+	for _, id := range p.messageIDs {
+		if err := s.queueSvc.Ack(id); err != nil {
+			s.logger.Error("cannot ack queued message", zap.Error(err), zap.Uint("message_id", id))
+		}
+	}
+}
+
+func (s *slackSvc) fail(p *pending, reason error) {
+	for _, id := range p.messageIDs {
+		if err := s.queueSvc.Fail(id, reason); err != nil {
+			s.logger.Error("cannot fail queued message", zap.Error(err), zap.Uint("message_id", id))
+		}
+	}
+}
+
+func (s *slackSvc) failAll(batch map[string]*pending, reason error) {
+	for _, p := range batch {
+		s.fail(p, reason)
+	}
+}
@@ -0,0 +1,29 @@
+package decay
+
+import (
+	"time"
+
+	"github.com/webuild-community/core/model"
+	"gorm.io/gorm"
+)
+
+type pg struct {
+	db  *gorm.DB
+	cfg Config
+}
+
+// NewPGService --
+func NewPGService(db *gorm.DB, cfg Config) Service {
+	return &pg{db: db, cfg: cfg}
+}
+
+func (s *pg) Apply(now time.Time) (int64, error) {
+	if !s.cfg.Enabled() {
+		return 0, nil
+	}
+
+	res := s.db.Model(&model.User{}).
+		Where("last_active_at < ? AND ranking_score > 0", now.Add(-s.cfg.InactiveAfter)).
+		Update("ranking_score", gorm.Expr("FLOOR(ranking_score * ?)", 1-s.cfg.Rate))
+	return res.RowsAffected, res.Error
+}
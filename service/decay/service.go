@@ -0,0 +1,37 @@
+package decay
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config of the ranking score decay, read from the environment
+type Config struct {
+	// Rate is the share of the ranking score lost on each run, decay is disabled when 0
+	Rate float64
+	// InactiveAfter is how long a user can stay silent before their score decays
+	InactiveAfter time.Duration
+}
+
+// NewConfigFromEnv reads DECAY_RATE and DECAY_INACTIVE_DAYS
+func NewConfigFromEnv() Config {
+	cfg := Config{InactiveAfter: 30 * 24 * time.Hour}
+	if v, err := strconv.ParseFloat(os.Getenv("DECAY_RATE"), 64); err == nil && v > 0 && v < 1 {
+		cfg.Rate = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("DECAY_INACTIVE_DAYS")); err == nil && v > 0 {
+		cfg.InactiveAfter = time.Duration(v) * 24 * time.Hour
+	}
+	return cfg
+}
+
+// Enabled reports whether rankings use the decayed score
+func (c Config) Enabled() bool {
+	return c.Rate > 0
+}
+
+type Service interface {
+	// Apply decays the ranking score of users inactive at now and returns how many were affected
+	Apply(now time.Time) (int64, error)
+}
@@ -0,0 +1,44 @@
+package dedup
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/webuild-community/core/model"
+	"gorm.io/gorm"
+)
+
+var suppressed = expvar.NewInt("dedup_suppressed_retries")
+
+type pg struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewPGService --
+func NewPGService(db *gorm.DB, ttl time.Duration) Service {
+	return &pg{db: db, ttl: ttl}
+}
+
+func (s *pg) Seen(key string) (bool, error) {
+	// an expired key is refreshed instead of being reported as a duplicate
+	res := s.db.Exec(`
+		INSERT INTO processed_event (id, created_at) VALUES (?, now())
+		ON CONFLICT (id) DO UPDATE SET created_at = now()
+		WHERE processed_event.created_at < ?`,
+		key, time.Now().Add(-s.ttl),
+	)
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		suppressed.Add(1)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *pg) Purge() error {
+	return s.db.Where("created_at < ?", time.Now().Add(-s.ttl)).Delete(&model.ProcessedEvent{}).Error
+}
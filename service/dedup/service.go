@@ -0,0 +1,8 @@
+package dedup
+
+type Service interface {
+	// Seen records key and reports whether it was already recorded within the TTL
+	Seen(key string) (bool, error)
+	// Purge forgets keys older than the TTL
+	Purge() error
+}
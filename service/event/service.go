@@ -8,6 +8,12 @@ type Service interface {
 	Verify(header http.Header, body []byte) (interface{}, error)
 	Profile(channelID, userID string) error
 	Register(userID string) error
-	Top(channelID string) error
+	Top(channelID, userID, args string) error
 	Drop(userID string) error
+	Tip(channelID, userID, messageTS, args string) error
+	Notify(channelID, userID, args string) error
+	Kudos(channelID, userID, messageTS, args string) error
+	Quests(channelID, userID string) error
+	// Home publishes the App Home tab of userID
+	Home(userID string) error
 }
@@ -1,17 +1,26 @@
 package event
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
-	"github.com/dstotijn/go-notion"
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/badge"
+	"github.com/webuild-community/core/service/item"
+	"github.com/webuild-community/core/service/kudos"
+	"github.com/webuild-community/core/service/leaderboard"
+	"github.com/webuild-community/core/service/level"
+	"github.com/webuild-community/core/service/notification"
+	"github.com/webuild-community/core/service/quest"
+	"github.com/webuild-community/core/service/slackutil"
+	"github.com/webuild-community/core/service/streak"
+	"github.com/webuild-community/core/service/tip"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -21,11 +30,18 @@ type slackSvc struct {
 	logger         *zap.Logger
 	db             *gorm.DB
 	slackClient    *slack.Client
-	notionClient   *notion.Client
+	itemSvc        item.Service
+	tipSvc         tip.Service
+	curve          level.Service
+	notifySvc      notification.Service
+	leaderboardSvc leaderboard.Service
+	badgeSvc       badge.Service
+	kudosSvc       kudos.Service
+	questSvc       quest.Service
 }
 
 // NewSlackService --
-func NewSlackService(logger *zap.Logger, db *gorm.DB, slackClient *slack.Client, notionClient *notion.Client) Service {
+func NewSlackService(logger *zap.Logger, db *gorm.DB, slackClient *slack.Client, itemSvc item.Service, tipSvc tip.Service, curve level.Service, notifySvc notification.Service, leaderboardSvc leaderboard.Service, badgeSvc badge.Service, kudosSvc kudos.Service, questSvc quest.Service) Service {
 	githubClientID := os.Getenv("GITHUB_CLIENT_ID")
 	if len(githubClientID) == 0 {
 		logger.Fatal("GITHUB_CLIENT_ID is not set")
@@ -35,7 +51,14 @@ func NewSlackService(logger *zap.Logger, db *gorm.DB, slackClient *slack.Client,
 		logger:         logger,
 		db:             db,
 		slackClient:    slackClient,
-		notionClient:   notionClient,
+		itemSvc:        itemSvc,
+		tipSvc:         tipSvc,
+		curve:          curve,
+		notifySvc:      notifySvc,
+		leaderboardSvc: leaderboardSvc,
+		badgeSvc:       badgeSvc,
+		kudosSvc:       kudosSvc,
+		questSvc:       questSvc,
 	}
 }
 
@@ -70,26 +93,23 @@ func (s *slackSvc) Profile(channelID, userID string) error {
 		return err
 	}
 
-	payload := fmt.Sprintf("Exp: `%d`, level: %d", user.Exp, user.Level)
-	_, _, err = s.slackClient.PostMessage(channelID, slack.MsgOptionText(payload, false))
-	return err
-}
+	payload := fmt.Sprintf("Exp: `%d`, level: %d, `%d` exp to next level", user.Exp, user.Level, level.ToNext(s.curve, user.Exp))
+	if days := streak.Current(user, time.Now()); days > 0 {
+		payload += fmt.Sprintf("\nStreak: :fire: %d day(s), longest %d", days, user.LongestStreak)
+	}
 
-func (s *slackSvc) dmUser(userID string, options ...slack.MsgOption) error {
-	channel, _, _, err := s.slackClient.OpenConversation(&slack.OpenConversationParameters{
-		Users:    []string{userID},
-		ReturnIM: true,
-	})
+	badges, err := s.badgeSvc.List(userID)
 	if err != nil {
-		s.logger.Error("open direct message failed", zap.Error(err))
 		return err
 	}
-
-	if _, _, _, err := s.slackClient.SendMessage(channel.ID, options...); err != nil {
-		s.logger.Error("send message failed", zap.Error(err))
-		return err
+	if len(badges) > 0 {
+		payload += "\nBadges:"
+		for _, b := range badges {
+			payload += fmt.Sprintf(" %s %s", b.Emoji, b.Name)
+		}
 	}
-	return nil
+	_, _, err = s.slackClient.PostMessage(channelID, slack.MsgOptionText(payload, false))
+	return err
 }
 
 func (s *slackSvc) Register(userID string) error {
@@ -119,7 +139,7 @@ func (s *slackSvc) Register(userID string) error {
 
 	// User is already exists
 	if user.GithubUsername != "" {
-		return s.dmUser(userID, slack.MsgOptionText(
+		return slackutil.DM(s.slackClient, s.logger, userID, slack.MsgOptionText(
 			"*Account registered*\nWelcome to WeXu, your account has been registered!",
 			true,
 		))
@@ -130,22 +150,43 @@ func (s *slackSvc) Register(userID string) error {
 	blockText := slack.NewTextBlockObject("mrkdwn", text, false, true)
 	section := slack.NewSectionBlock(blockText, nil, nil)
 
-	return s.dmUser(userID, slack.MsgOptionBlocks(section))
+	return slackutil.DM(s.slackClient, s.logger, userID, slack.MsgOptionBlocks(section))
 }
 
-func (s *slackSvc) Top(channelID string) error {
-	users := []model.User{}
-	err := s.db.Model(model.User{}).Order("exp DESC").Limit(10).Find(&users).Error
+func (s *slackSvc) Top(channelID, userID, args string) error {
+	filter, title, err := leaderboard.ParseFilter(args, time.Now())
 	if err != nil {
+		_, err := s.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionText(err.Error(), false))
 		return err
 	}
-	if len(users) == 0 {
-		if _, _, _, err := s.slackClient.SendMessage(channelID, slack.MsgOptionText("No users reached the top", false)); err != nil {
+
+	entries, err := s.leaderboardSvc.Top(filter, 10)
+	if err != nil {
+		if errors.Is(err, leaderboard.ErrNoActiveSeason) {
+			_, err := s.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionText("No season is running", false))
 			return err
 		}
+		return err
+	}
+	if len(entries) == 0 {
+		_, _, _, err := s.slackClient.SendMessage(channelID, slack.MsgOptionText("No users reached the top", false))
+		return err
+	}
+
+	blocks := buildBlockUserTopMessage(title, entries)
+
+	// show the caller where they stand when they are not on the board
+	if !containsUser(entries, userID) {
+		own, err := s.leaderboardSvc.Rank(filter, userID)
+		if err != nil {
+			return err
+		}
+		if own != nil {
+			text := fmt.Sprintf("Your rank: #%d - %d exp", own.Rank, own.Score)
+			blocks = append(blocks, slack.NewDividerBlock(), slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", text, false, false)))
+		}
 	}
 
-	blocks := buildBlockUserTopMessage(users)
 	if _, _, _, err := s.slackClient.SendMessage(channelID, slack.MsgOptionBlocks(blocks...)); err != nil {
 		return err
 	}
@@ -153,29 +194,38 @@ func (s *slackSvc) Top(channelID string) error {
 	return nil
 }
 
-func buildBlockUserTopMessage(users []model.User) []slack.Block {
+func containsUser(entries []leaderboard.Entry, userID string) bool {
+	for _, e := range entries {
+		if e.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func buildBlockUserTopMessage(title string, entries []leaderboard.Entry) []slack.Block {
 	divider := slack.NewDividerBlock()
 	blocks := make([]slack.Block, 0)
 
-	header := slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", "*Top Users*", false, false), nil, nil)
+	header := slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*Top Users* - %s", title), false, false), nil, nil)
 	blocks = append(blocks, header)
 	blocks = append(blocks, divider)
 
-	for index, user := range users {
-		if user.DisplayName == "" {
-			user.DisplayName = "Unknown"
+	for index, entry := range entries {
+		if entry.DisplayName == "" {
+			entry.DisplayName = "Unknown"
 		}
-		if user.ImageOriginal == "" {
-			user.ImageOriginal = "https://upload.wikimedia.org/wikipedia/commons/8/89/Portrait_Placeholder.png"
+		if entry.ImageOriginal == "" {
+			entry.ImageOriginal = "https://upload.wikimedia.org/wikipedia/commons/8/89/Portrait_Placeholder.png"
 		}
 
-		userTxt := fmt.Sprintf("%s - %d lvl", user.DisplayName, user.Level)
+		userTxt := fmt.Sprintf("#%d %s - %d exp - %d lvl", entry.Rank, entry.DisplayName, entry.Score, entry.Level)
 		userBlock := slack.NewTextBlockObject("mrkdwn", userTxt, false, false)
-		imageBlock := slack.NewImageBlockElement(user.ImageOriginal, user.DisplayName)
+		imageBlock := slack.NewImageBlockElement(entry.ImageOriginal, entry.DisplayName)
 		sectionBlock := slack.NewSectionBlock(userBlock, nil, slack.NewAccessory(imageBlock))
 
 		blocks = append(blocks, sectionBlock)
-		if index != len(users)-1 {
+		if index != len(entries)-1 {
 			blocks = append(blocks, divider)
 		}
 	}
@@ -184,13 +234,10 @@ func buildBlockUserTopMessage(users []model.User) []slack.Block {
 }
 
 func (s *slackSvc) Drop(userID string) error {
-	isExpired := false
-	items, err := s.notionClient.QueryDatabase(context.Background(), os.Getenv("NOTION_DATABASE_ID"), &notion.DatabaseQuery{Filter: &notion.DatabaseQueryFilter{
-		Property: "Expired",
-		Checkbox: &notion.CheckboxDatabaseQueryFilter{Equals: &isExpired},
-	}})
+	items, err := s.itemSvc.List()
 	if err != nil {
 		s.logger.Error("cannot fetch items", zap.Error(err))
+		return err
 	}
 
 	pretext := fmt.Sprintf("*Drop Items*\n")
@@ -202,37 +249,132 @@ func (s *slackSvc) Drop(userID string) error {
 	}
 
 	blockset := []slack.Block{}
-	for _, v := range items.Results {
-		properties, ok := v.Properties.(notion.DatabasePageProperties)
-		if !ok {
-			continue
-		}
-
-		if len(properties["Name"].Title) == 0 ||
-			properties["Redeemed"].Number == nil ||
-			properties["Quantity"].Number == nil ||
-			properties["Price"].Number == nil {
-			continue
-		}
-
-		if *properties["Redeemed"].Number >= *properties["Quantity"].Number {
-			continue
-		}
-
-		text := fmt.Sprintf("*%v* (%v/%v)\n", properties["Name"].Title[0].PlainText, *properties["Redeemed"].Number, *properties["Quantity"].Number)
-		if len(properties["Description"].Title) > 0 {
-			text += fmt.Sprintf("Description: %v\n", properties["Description"].Title[0].PlainText)
+	for _, v := range items {
+		text := fmt.Sprintf("*%v* (%v/%v)\n", v.Name, v.Redeemed, v.Quantity)
+		if v.Description != "" {
+			text += fmt.Sprintf("Description: %v\n", v.Description)
 		}
-		text += fmt.Sprintf("%v RDF", *properties["Price"].Number)
+		text += fmt.Sprintf("%v RDF", v.Price)
 
 		redeemBtnTxt := slack.NewTextBlockObject("plain_text", "Redeem", false, false)
 		redeemButton := slack.NewButtonBlockElement("", v.ID, redeemBtnTxt)
 		redeemButton.Style = "primary"
 		block := slack.NewTextBlockObject("mrkdwn", text, false, true)
 		sectionBlock := slack.NewSectionBlock(block, nil, slack.NewAccessory(redeemButton))
+		if v.ImageURL != "" {
+			blockset = append(blockset, slack.NewImageBlock(v.ImageURL, v.Name, "", nil))
+		}
 		blockset = append(blockset, sectionBlock)
 	}
 	attachment.Blocks = slack.Blocks{BlockSet: blockset}
 
-	return s.dmUser(userID, slack.MsgOptionBlocks(sectionBlockPretext), slack.MsgOptionAttachments(attachment))
+	return slackutil.DM(s.slackClient, s.logger, userID, slack.MsgOptionBlocks(sectionBlockPretext), slack.MsgOptionAttachments(attachment))
+}
+
+func (s *slackSvc) Tip(channelID, userID, messageTS, args string) error {
+	toUserID, amount, reason, err := tip.ParseArgs(args)
+	if err == nil {
+		_, err = s.tipSvc.Send(messageTS, userID, toUserID, amount, reason)
+	}
+	if err != nil {
+		if tip.IsRejection(err) {
+			_, err = s.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionText(fmt.Sprintf("Cannot send tip: %v", err), false))
+			return err
+		}
+		s.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionText("Please try again later", false))
+		return err
+	}
+
+	return nil
+}
+
+func (s *slackSvc) Kudos(channelID, userID, messageTS, args string) error {
+	toUserID, reason, err := kudos.ParseArgs(args)
+	if err == nil {
+		_, err = s.kudosSvc.Give(messageTS, userID, toUserID, channelID, reason)
+	}
+	if err != nil {
+		if kudos.IsRejection(err) {
+			_, err = s.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionText(fmt.Sprintf("Cannot give kudos: %v", err), false))
+			return err
+		}
+		s.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionText("Please try again later", false))
+		return err
+	}
+
+	return nil
+}
+
+func (s *slackSvc) Quests(channelID, userID string) error {
+	progress, err := s.questSvc.Progress(userID)
+	if err != nil {
+		s.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionText("Please try again later", false))
+		return err
+	}
+
+	_, err = s.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionBlocks(questBlocks(progress)...))
+	return err
+}
+
+func (s *slackSvc) Home(userID string) error {
+	progress, err := s.questSvc.Progress(userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.slackClient.PublishView(userID, slack.HomeTabViewRequest{
+		Type:   slack.VTHomeTab,
+		Blocks: slack.Blocks{BlockSet: questBlocks(progress)},
+	}, "")
+	return err
+}
+
+// questBlocks renders the active quests and the user's progress on them
+func questBlocks(progress []quest.Progress) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, "Quests", false, false)),
+	}
+	if len(progress) == 0 {
+		return append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, "There is no active quest right now", false, false), nil, nil))
+	}
+
+	for _, p := range progress {
+		status := fmt.Sprintf("`%d/%d`", p.Count, p.Quest.Target)
+		if p.Completed {
+			status = ":white_check_mark: completed"
+		}
+		text := fmt.Sprintf("*%s* %s", p.Quest.Name, status)
+		if p.Quest.Description != "" {
+			text += "\n" + p.Quest.Description
+		}
+		if p.Quest.RewardExp > 0 || p.Quest.RewardRDF > 0 {
+			text += fmt.Sprintf("\nReward: `%d` exp, `%v` RDF", p.Quest.RewardExp, p.Quest.RewardRDF)
+		}
+		if p.Quest.EndsAt != nil {
+			text += fmt.Sprintf("\nEnds on %s", p.Quest.EndsAt.Format("2006-01-02"))
+		}
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil))
+	}
+	return blocks
+}
+
+func (s *slackSvc) Notify(channelID, userID, args string) error {
+	var optOut bool
+	switch args {
+	case "off":
+		optOut = true
+	case "on":
+		optOut = false
+	default:
+		_, err := s.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionText("Usage: `$notify on` or `$notify off`", false))
+		return err
+	}
+
+	if err := s.notifySvc.SetOptOut(userID, optOut); err != nil {
+		s.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionText("Please try again later", false))
+		return err
+	}
+
+	_, err := s.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionText(fmt.Sprintf("Level notifications are now %s", args), false))
+	return err
 }
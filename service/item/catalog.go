@@ -0,0 +1,132 @@
+package item
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/webuild-community/core/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// item sources selectable with ITEM_SOURCE
+const (
+	SourcePostgres = "postgres"
+	SourceNotion   = "notion"
+	SourceFile     = "file"
+)
+
+// Catalog is an external list of items the item table is imported from
+type Catalog interface {
+	// Items returns every valid item of the catalog, expired ones included, and the ids of
+	// rows that were skipped as malformed
+	Items() ([]model.Item, []string, error)
+}
+
+// RedeemedWriter is implemented by catalogs that display redeemed counts
+type RedeemedWriter interface {
+	WriteRedeemed(counts map[string]uint) error
+}
+
+// Validator is implemented by catalogs that can check their schema before anything is imported
+type Validator interface {
+	Validate() error
+}
+
+// SchemaError lists what is wrong with a catalog's schema
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return "invalid catalog schema: " + strings.Join(e.Problems, "; ")
+}
+
+// NewCatalogFromEnv builds the catalog chosen by ITEM_SOURCE, it returns nil for postgres
+// where items are managed in the item table directly
+func NewCatalogFromEnv(logger *zap.Logger) (Catalog, error) {
+	source := os.Getenv("ITEM_SOURCE")
+	if source == "" && os.Getenv("NOTION_DATABASE_ID") != "" {
+		source = SourceNotion
+	}
+
+	switch source {
+	case "", SourcePostgres:
+		return nil, nil
+	case SourceNotion:
+		return NewNotionCatalog(logger, notion.NewClient(os.Getenv("NOTION_SECRET_KEY")), os.Getenv("NOTION_DATABASE_ID")), nil
+	case SourceFile:
+		return NewFileCatalog(logger, os.Getenv("ITEM_CATALOG_FILE")), nil
+	}
+	return nil, fmt.Errorf("unknown item source %q", source)
+}
+
+type catalogSync struct {
+	logger  *zap.Logger
+	db      *gorm.DB
+	catalog Catalog
+}
+
+// NewSyncer --
+func NewSyncer(logger *zap.Logger, db *gorm.DB, catalog Catalog) Syncer {
+	return &catalogSync{
+		logger:  logger,
+		db:      db,
+		catalog: catalog,
+	}
+}
+
+func (s *catalogSync) Import() (int, error) {
+	items, skipped, err := s.catalog.Items()
+	if err != nil {
+		return 0, err
+	}
+	// an empty catalog is more likely a misconfiguration than a closed shop
+	if len(items) == 0 {
+		s.logger.Warn("item catalog is empty, nothing imported")
+		return 0, nil
+	}
+
+	// malformed rows are still in the catalog, their items stay as they were until the row is fixed
+	ids := make([]string, 0, len(items)+len(skipped))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	ids = append(ids, skipped...)
+	return len(items), s.db.Transaction(func(tx *gorm.DB) error {
+		// redeemed counts are owned by the item table once an item is imported,
+		// items added back to the catalog are restored
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "description", "image_url", "quantity", "price", "expires_at", "updated_at", "deleted_at"}),
+		}).Create(&items).Error; err != nil {
+			return err
+		}
+
+		// items removed from the catalog leave the shop, their orders keep pointing at them
+		return tx.Model(&model.Item{}).
+			Where("deleted_at IS NULL AND id NOT IN ?", ids).
+			Update("deleted_at", time.Now()).Error
+	})
+}
+
+func (s *catalogSync) Export() error {
+	w, ok := s.catalog.(RedeemedWriter)
+	if !ok {
+		return nil
+	}
+
+	items := []model.Item{}
+	if err := s.db.Where("deleted_at IS NULL").Find(&items).Error; err != nil {
+		return err
+	}
+	counts := make(map[string]uint, len(items))
+	for _, item := range items {
+		counts[item.ID] = item.Redeemed
+	}
+	return w.WriteRedeemed(counts)
+}
@@ -0,0 +1,111 @@
+package item
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/webuild-community/core/model"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+type fileCatalog struct {
+	logger *zap.Logger
+	path   string
+}
+
+// NewFileCatalog reads items from a JSON or YAML file, see items.example.json
+func NewFileCatalog(logger *zap.Logger, path string) Catalog {
+	return &fileCatalog{logger: logger, path: path}
+}
+
+// fileItem is one row of the catalog file, numbers are pointers to tell missing ones apart
+type fileItem struct {
+	model.Item
+	Quantity *float64 `json:"quantity"`
+	Price    *float64 `json:"price"`
+}
+
+func (c *fileCatalog) Items() ([]model.Item, []string, error) {
+	buf, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// yaml rows go through json so both formats share the item's json fields
+	switch strings.ToLower(filepath.Ext(c.path)) {
+	case ".yaml", ".yml":
+		rows := []interface{}{}
+		if err := yaml.Unmarshal(buf, &rows); err != nil {
+			return nil, nil, err
+		}
+		if buf, err = json.Marshal(rows); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	rows := []json.RawMessage{}
+	if err := json.Unmarshal(buf, &rows); err != nil {
+		return nil, nil, err
+	}
+	items := make([]model.Item, 0, len(rows))
+	skipped := []string{}
+	for i, row := range rows {
+		item, err := itemFromRow(row)
+		if err != nil {
+			c.logger.Warn("skipped catalog item", zap.String("reason", err.Error()), zap.Int("row", i+1), zap.String("file", c.path))
+			if id := rowID(row); id != "" {
+				skipped = append(skipped, id)
+			}
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, skipped, nil
+}
+
+// rowID reads the id of a row that may be malformed otherwise, it is empty when there is none
+func rowID(row json.RawMessage) string {
+	r := struct {
+		ID interface{} `json:"id"`
+	}{}
+	if err := json.Unmarshal(row, &r); err != nil {
+		return ""
+	}
+	id, _ := r.ID.(string)
+	return id
+}
+
+func itemFromRow(row json.RawMessage) (model.Item, error) {
+	r := fileItem{}
+	if err := json.Unmarshal(row, &r); err != nil {
+		return model.Item{}, err
+	}
+
+	item := r.Item
+	if item.ID == "" {
+		return model.Item{}, errors.New(`"id" is empty`)
+	}
+	if item.Name == "" {
+		return model.Item{}, errors.New(`"name" is empty`)
+	}
+	if r.Quantity == nil {
+		return model.Item{}, errors.New(`"quantity" is empty`)
+	}
+	if *r.Quantity < 0 || *r.Quantity != math.Trunc(*r.Quantity) {
+		return model.Item{}, fmt.Errorf(`"quantity" must be a whole positive number, got %v`, *r.Quantity)
+	}
+	item.Quantity = uint(*r.Quantity)
+	if r.Price == nil || *r.Price < 0 {
+		return model.Item{}, errors.New(`"price" must be a positive number`)
+	}
+	item.Price = *r.Price
+	// redeemed counts come from the item table only
+	item.Redeemed = 0
+	return item, nil
+}
@@ -0,0 +1,105 @@
+package item
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestItemFromRow(t *testing.T) {
+	tests := []struct {
+		row      string
+		quantity uint
+		price    float64
+		wantErr  bool
+	}{
+		{row: `{"id": "mug", "name": "Mug", "price": 20, "quantity": 5}`, quantity: 5, price: 20},
+		{row: `{"id": "mug", "name": "Mug", "price": 0, "quantity": 0, "redeemed": 3}`},
+		{row: `{"name": "Mug", "price": 20, "quantity": 5}`, wantErr: true},
+		{row: `{"id": "mug", "price": 20, "quantity": 5}`, wantErr: true},
+		{row: `{"id": "mug", "name": "Mug", "quantity": 5}`, wantErr: true},
+		{row: `{"id": "mug", "name": "Mug", "price": -1, "quantity": 5}`, wantErr: true},
+		{row: `{"id": "mug", "name": "Mug", "price": 20}`, wantErr: true},
+		{row: `{"id": "mug", "name": "Mug", "price": 20, "quantity": 1.5}`, wantErr: true},
+		{row: `{"id": "mug", "name": "Mug", "price": 20, "quantity": -2}`, wantErr: true},
+		{row: `{"id": 12, "name": "Mug", "price": 20, "quantity": 5}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.row, func(t *testing.T) {
+			item, err := itemFromRow([]byte(tt.row))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("itemFromRow() = %+v, want an error", item)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("itemFromRow() error = %v", err)
+			}
+			if item.ID != "mug" || item.Name != "Mug" || item.Quantity != tt.quantity || item.Price != tt.price || item.Redeemed != 0 {
+				t.Errorf("itemFromRow() = %+v", item)
+			}
+		})
+	}
+}
+
+func TestFileCatalogItems(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"items.json": `[
+			{"id": "mug", "name": "Mug", "price": 20, "quantity": 5, "expires_at": "2021-12-31T00:00:00Z"},
+			{"name": "No id", "price": 1, "quantity": 1},
+			{"id": "cap", "name": "Cap", "price": 10}
+		]`,
+		"items.yaml": `
+- id: mug
+  name: Mug
+  price: 20
+  quantity: 5
+  expires_at: 2021-12-31T00:00:00Z
+- name: No id
+  price: 1
+  quantity: 1
+- id: cap
+  name: Cap
+  price: 10
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			items, skipped, err := NewFileCatalog(zap.NewNop(), path).Items()
+			if err != nil {
+				t.Fatalf("Items() error = %v", err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("Items() = %+v, want only the valid row", items)
+			}
+			if items[0].ID != "mug" || items[0].ExpiresAt == nil || items[0].ExpiresAt.Year() != 2021 {
+				t.Errorf("Items() = %+v", items[0])
+			}
+			// the row without an id cannot be matched to an item, the one without a quantity can
+			if len(skipped) != 1 || skipped[0] != "cap" {
+				t.Errorf("Items() skipped %v, want [cap]", skipped)
+			}
+		})
+	}
+}
+
+func TestFileCatalogExample(t *testing.T) {
+	items, _, err := NewFileCatalog(zap.NewNop(), "../../items.example.json").Items()
+	if err != nil {
+		t.Fatalf("cannot read the example catalog: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("example catalog has %d valid items, want 2", len(items))
+	}
+}
@@ -3,76 +3,210 @@ package item
 import (
 	"context"
 	"errors"
-	"os"
+	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/dstotijn/go-notion"
 	"github.com/webuild-community/core/model"
 	"go.uber.org/zap"
 )
 
-type notionSvc struct {
+type notionCatalog struct {
 	logger       *zap.Logger
 	notionClient *notion.Client
+	databaseID   string
 }
 
-// NewNotionService --
-func NewNotionService(logger *zap.Logger, notionClient *notion.Client) Service {
-	return &notionSvc{
+// NewNotionCatalog --
+func NewNotionCatalog(logger *zap.Logger, notionClient *notion.Client, databaseID string) Catalog {
+	return &notionCatalog{
 		logger:       logger,
 		notionClient: notionClient,
+		databaseID:   databaseID,
 	}
 }
 
-func (s *notionSvc) Find(id string) (*model.Item, error) {
-	isExpired := false
-	items, err := s.notionClient.QueryDatabase(context.Background(), os.Getenv("NOTION_DATABASE_ID"), &notion.DatabaseQuery{Filter: &notion.DatabaseQueryFilter{
-		Property: "Expired",
-		Checkbox: &notion.CheckboxDatabaseQueryFilter{Equals: &isExpired},
-	}})
+func (c *notionCatalog) Items() ([]model.Item, []string, error) {
+	pages, err := c.pages()
 	if err != nil {
-		s.logger.Error("cannot fetch items", zap.Error(err))
-		return nil, err
+		return nil, nil, err
 	}
 
-	for _, v := range items.Results {
-		if v.ID != id {
+	items := make([]model.Item, 0, len(pages))
+	skipped := []string{}
+	for _, page := range pages {
+		if page.Archived {
 			continue
 		}
+		item, err := itemFromPage(page)
+		if err != nil {
+			c.logger.Warn("skipped notion item", zap.String("reason", err.Error()), zap.String("item_id", page.ID))
+			skipped = append(skipped, page.ID)
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, skipped, nil
+}
 
-		properties, ok := v.Properties.(notion.DatabasePageProperties)
+func (c *notionCatalog) WriteRedeemed(counts map[string]uint) error {
+	pages, err := c.pages()
+	if err != nil {
+		return err
+	}
+
+	for _, page := range pages {
+		count, ok := counts[page.ID]
+		if !ok {
+			continue
+		}
+		properties, ok := page.Properties.(notion.DatabasePageProperties)
 		if !ok {
 			continue
 		}
+		if n := properties["Redeemed"].Number; n != nil && uint(*n) == count {
+			continue
+		}
 
-		return &model.Item{
-			ID:       id,
-			Name:     properties["Name"].Title[0].PlainText,
-			Quantity: uint(*properties["Quantity"].Number),
-			Redeemed: uint(*properties["Redeemed"].Number),
-			Price:    *properties["Price"].Number,
-		}, nil
+		value := float64(count)
+		if _, err := c.notionClient.UpdatePageProps(context.Background(), page.ID, notion.UpdatePageParams{
+			DatabasePageProperties: &notion.DatabasePageProperties{
+				"Redeemed": notion.DatabasePageProperty{Type: notion.DBPropTypeNumber, Number: &value},
+			},
+		}); err != nil {
+			c.logger.Error("cannot export redeemed count", zap.Error(err), zap.String("item_id", page.ID))
+		}
 	}
+	return nil
+}
 
-	return nil, errors.New("item not found")
+// pages reads every page of the notion database
+func (c *notionCatalog) pages() ([]notion.Page, error) {
+	pages := []notion.Page{}
+	query := &notion.DatabaseQuery{}
+	for {
+		res, err := c.notionClient.QueryDatabase(context.Background(), c.databaseID, query)
+		if err != nil {
+			c.logger.Error("cannot fetch items", zap.Error(err))
+			return nil, err
+		}
+		pages = append(pages, res.Results...)
+		if !res.HasMore || res.NextCursor == nil {
+			return pages, nil
+		}
+		query.StartCursor = *res.NextCursor
+	}
 }
 
-func (s *notionSvc) Redeem(itemID, userID string) error {
-	s.logger.Info("handling Redeem", zap.String("item_id", itemID))
+// notionProperty is a column of the notion item database
+type notionProperty struct {
+	Name     string
+	Type     notion.DatabasePropertyType
+	Required bool
+}
 
-	item, err := s.Find(itemID)
+var notionSchema = []notionProperty{
+	{Name: "Name", Type: notion.DBPropTypeTitle, Required: true},
+	{Name: "Quantity", Type: notion.DBPropTypeNumber, Required: true},
+	{Name: "Price", Type: notion.DBPropTypeNumber, Required: true},
+	{Name: "Redeemed", Type: notion.DBPropTypeNumber},
+	{Name: "Description", Type: notion.DBPropTypeRichText},
+	{Name: "Image", Type: notion.DBPropTypeURL},
+	{Name: "Expires", Type: notion.DBPropTypeDate},
+	{Name: "Expired", Type: notion.DBPropTypeCheckbox},
+}
+
+func (c *notionCatalog) Validate() error {
+	db, err := c.notionClient.FindDatabaseByID(context.Background(), c.databaseID)
 	if err != nil {
 		return err
 	}
 
-	redeemed := float64(item.Redeemed + 1)
-	s.notionClient.UpdatePageProps(context.Background(),
-		itemID,
-		notion.UpdatePageParams{DatabasePageProperties: &notion.DatabasePageProperties{
-			"Redeemed": notion.DatabasePageProperty{
-				Type:   "number",
-				Number: &redeemed,
-			}}},
-	)
-
+	problems := []string{}
+	for _, p := range notionSchema {
+		prop, ok := db.Properties[p.Name]
+		switch {
+		case !ok && p.Required:
+			problems = append(problems, fmt.Sprintf("%q is missing, it must be a %s", p.Name, p.Type))
+		case ok && prop.Type != p.Type:
+			problems = append(problems, fmt.Sprintf("%q is a %s, it must be a %s", p.Name, prop.Type, p.Type))
+		}
+	}
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
 	return nil
 }
+
+// itemFromPage maps a row of the notion database to an item, rows with missing or invalid values are rejected
+func itemFromPage(page notion.Page) (model.Item, error) {
+	properties, ok := page.Properties.(notion.DatabasePageProperties)
+	if !ok {
+		return model.Item{}, errors.New("page is not a database row")
+	}
+	for _, p := range notionSchema {
+		prop, ok := properties[p.Name]
+		if ok && prop.Type != "" && prop.Type != p.Type {
+			return model.Item{}, fmt.Errorf("%q is a %s, it must be a %s", p.Name, prop.Type, p.Type)
+		}
+	}
+
+	item := model.Item{ID: page.ID}
+	item.Name = plainText(properties["Name"].Title)
+	if item.Name == "" {
+		return model.Item{}, errors.New(`"Name" is empty`)
+	}
+
+	quantity, err := count(properties, "Quantity", true)
+	if err != nil {
+		return model.Item{}, err
+	}
+	item.Quantity = quantity
+
+	price := properties["Price"].Number
+	if price == nil || *price < 0 {
+		return model.Item{}, errors.New(`"Price" must be a positive number`)
+	}
+	item.Price = *price
+
+	if item.Redeemed, err = count(properties, "Redeemed", false); err != nil {
+		return model.Item{}, err
+	}
+	item.Description = plainText(properties["Description"].RichText)
+	if url := properties["Image"].URL; url != nil {
+		item.ImageURL = *url
+	}
+	if date := properties["Expires"].Date; date != nil {
+		item.ExpiresAt = &date.Start.Time
+	}
+	if expired := properties["Expired"].Checkbox; expired != nil && *expired && item.ExpiresAt == nil {
+		now := time.Now()
+		item.ExpiresAt = &now
+	}
+	return item, nil
+}
+
+// count reads a property holding a whole, positive number
+func count(properties notion.DatabasePageProperties, name string, required bool) (uint, error) {
+	n := properties[name].Number
+	if n == nil {
+		if required {
+			return 0, fmt.Errorf("%q is empty", name)
+		}
+		return 0, nil
+	}
+	if *n < 0 || *n != math.Trunc(*n) {
+		return 0, fmt.Errorf("%q must be a whole positive number, got %v", name, *n)
+	}
+	return uint(*n), nil
+}
+
+func plainText(texts []notion.RichText) string {
+	res := ""
+	for _, t := range texts {
+		res += t.PlainText
+	}
+	return strings.TrimSpace(res)
+}
@@ -1,83 +1,96 @@
 package item
 
 import (
-	"context"
 	"errors"
-	"os"
+	"fmt"
 
-	"github.com/dstotijn/go-notion"
 	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/transaction"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type pg struct {
-	logger       *zap.Logger
-	notionClient *notion.Client
-	db           *gorm.DB
+	logger *zap.Logger
+	db     *gorm.DB
+	ledger transaction.Service
 }
 
 // NewPGService --
-func NewPGService(logger *zap.Logger, notionClient *notion.Client, db *gorm.DB) Service {
+func NewPGService(logger *zap.Logger, db *gorm.DB, ledger transaction.Service) Service {
 	return &pg{
-		logger:       logger,
-		notionClient: notionClient,
-		db:           db,
+		logger: logger,
+		db:     db,
+		ledger: ledger,
 	}
 }
 
-func (s *pg) Find(id string) (*model.Item, error) {
-	isExpired := false
-	items, err := s.notionClient.QueryDatabase(context.Background(), os.Getenv("NOTION_DATABASE_ID"), &notion.DatabaseQuery{Filter: &notion.DatabaseQueryFilter{
-		Property: "Expired",
-		Checkbox: &notion.CheckboxDatabaseQueryFilter{Equals: &isExpired},
-	}})
-	if err != nil {
-		s.logger.Error("cannot fetch items", zap.Error(err))
-		return nil, err
-	}
-
-	for _, v := range items.Results {
-		if v.ID != id {
-			continue
-		}
+// available scopes q to items that are neither deleted nor expired
+func available(q *gorm.DB) *gorm.DB {
+	return q.Where("deleted_at IS NULL AND (expires_at IS NULL OR expires_at > now())")
+}
 
-		properties, ok := v.Properties.(notion.DatabasePageProperties)
-		if !ok {
-			continue
+func (s *pg) Find(id string) (*model.Item, error) {
+	item := model.Item{}
+	if err := available(s.db).First(&item, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrItemNotFound
 		}
-
-		return &model.Item{
-			ID:       id,
-			Name:     properties["Name"].Title[0].PlainText,
-			Quantity: uint(*properties["Quantity"].Number),
-			Redeemed: uint(*properties["Redeemed"].Number),
-			Price:    *properties["Price"].Number,
-		}, nil
+		return nil, err
 	}
+	return &item, nil
+}
 
-	return nil, errors.New("item not found")
+func (s *pg) List() ([]model.Item, error) {
+	items := []model.Item{}
+	return items, available(s.db).Where("redeemed < quantity").Order("price, name").Find(&items).Error
 }
 
-func (s *pg) Redeem(itemID, userID string) error {
+func (s *pg) Redeem(itemID, userID string) (*model.Transaction, error) {
 	s.logger.Info("handling Redeem", zap.String("item_id", itemID))
 
 	item, err := s.Find(itemID)
 	if err != nil {
-		return err
-	}
-
-	txs := []model.Transaction{}
-	if err := s.db.Find(&txs, "item_id = ?", itemID).Error; err != nil {
-		return err
-	}
-	if len(txs) == int(item.Quantity) {
-		return errors.New("out of stock")
+		return nil, err
 	}
 
-	return s.db.Create(&model.Transaction{
+	t := model.Transaction{
 		ItemID: itemID,
 		UserID: userID,
 		Price:  item.Price,
-	}).Error
+		Status: model.OrderPending,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// reserve one unit, the row stays locked until commit so concurrent redeems
+		// wait here and a rollback hands the unit back
+		res := available(tx.Model(&model.Item{})).
+			Where("id = ? AND redeemed < quantity", itemID).
+			Update("redeemed", gorm.Expr("redeemed + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrOutOfStock
+		}
+
+		if err := tx.Create(&t).Error; err != nil {
+			return err
+		}
+
+		// the ledger locks the user row and rejects the debit when funds are short
+		entry, err := s.ledger.WithTx(tx).Debit(userID, item.Price, transaction.Entry{
+			Reason:         model.ReasonRedeem,
+			IdempotencyKey: fmt.Sprintf("redeem:%d", t.ID),
+			Memo:           item.Name,
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&t).Update("journal_entry_id", entry.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
 }
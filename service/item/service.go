@@ -1,8 +1,30 @@
 package item
 
-import "github.com/webuild-community/core/model"
+import (
+	"errors"
+
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/transaction"
+)
+
+var (
+	ErrItemNotFound        = errors.New("item not found")
+	ErrOutOfStock          = errors.New("sold out")
+	ErrInsufficientBalance = transaction.ErrInsufficientBalance
+)
 
 type Service interface {
 	Find(id string) (*model.Item, error)
-	Redeem(itemID, userID string) error
+	// List returns the items that can be redeemed now
+	List() ([]model.Item, error)
+	// Redeem pays for one unit of an item and returns the pending order
+	Redeem(itemID, userID string) (*model.Transaction, error)
+}
+
+// Syncer keeps the item table in sync with an external catalog
+type Syncer interface {
+	// Import upserts the items of the external catalog and returns how many were imported
+	Import() (int, error)
+	// Export writes redeemed counts back to the external catalog
+	Export() error
 }
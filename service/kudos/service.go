@@ -0,0 +1,78 @@
+package kudos
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/webuild-community/core/model"
+)
+
+var (
+	ErrInvalidArgs     = errors.New("usage: `@user for <reason>`")
+	ErrSelfKudos       = errors.New("you cannot give kudos to yourself")
+	ErrBotKudos        = errors.New("you cannot give kudos to a bot")
+	ErrBudgetExhausted = errors.New("you have given all your kudos for this week")
+)
+
+var mentionRe = regexp.MustCompile(`^<@([A-Z0-9]+)(\|[^>]*)?>$`)
+
+// Config of kudos, read from the environment
+type Config struct {
+	// Exp granted to the receiver of each kudos
+	Exp int64
+	// WeeklyBudget is how many kudos a member can give per week, 0 means unlimited
+	WeeklyBudget int64
+	// Channel receives the shout-outs, they are posted where the kudos was given when empty
+	Channel string
+}
+
+// NewConfigFromEnv reads KUDOS_EXP, KUDOS_WEEKLY_BUDGET and KUDOS_CHANNEL
+func NewConfigFromEnv() Config {
+	cfg := Config{Exp: 5, WeeklyBudget: 5, Channel: os.Getenv("KUDOS_CHANNEL")}
+	if v, err := strconv.ParseInt(os.Getenv("KUDOS_EXP"), 10, 64); err == nil && v > 0 {
+		cfg.Exp = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("KUDOS_WEEKLY_BUDGET"), 10, 64); err == nil && v >= 0 {
+		cfg.WeeklyBudget = v
+	}
+	return cfg
+}
+
+type Service interface {
+	Give(key, fromUserID, toUserID, channelID, reason string) (*model.Kudos, error)
+}
+
+// IsRejection reports whether err is a rule violation that should be shown to the giver
+func IsRejection(err error) bool {
+	for _, e := range []error{ErrInvalidArgs, ErrSelfKudos, ErrBotKudos, ErrBudgetExhausted} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseArgs parses `<@U123> for reason` as sent by slack for both $kudos and /kudos
+func ParseArgs(text string) (toUserID string, reason string, err error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return "", "", ErrInvalidArgs
+	}
+
+	m := mentionRe.FindStringSubmatch(fields[0])
+	if m == nil {
+		return "", "", ErrInvalidArgs
+	}
+
+	if strings.EqualFold(fields[1], "for") {
+		fields = fields[1:]
+	}
+	reason = strings.Join(fields[1:], " ")
+	if reason == "" {
+		return "", "", ErrInvalidArgs
+	}
+	return m[1], reason, nil
+}
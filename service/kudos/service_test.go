@@ -0,0 +1,44 @@
+package kudos
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		text    string
+		to      string
+		reason  string
+		wantErr bool
+	}{
+		{text: "<@U0123> for the great talk", to: "U0123", reason: "the great talk"},
+		{text: "<@U0123|alice> FOR fixing the build", to: "U0123", reason: "fixing the build"},
+		{text: "<@U0123> helping with onboarding", to: "U0123", reason: "helping with onboarding"},
+		{text: "<@U0123> for for real", to: "U0123", reason: "for real"},
+		{text: "  <@U0123>   for   spacing  ", to: "U0123", reason: "spacing"},
+		{text: "", wantErr: true},
+		{text: "<@U0123>", wantErr: true},
+		{text: "<@U0123> for", wantErr: true},
+		{text: "@alice for the talk", wantErr: true},
+		{text: "for <@U0123>", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			to, reason, err := ParseArgs(tt.text)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidArgs) {
+					t.Errorf("ParseArgs(%q) error = %v, want ErrInvalidArgs", tt.text, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseArgs(%q) error = %v", tt.text, err)
+			}
+			if to != tt.to || reason != tt.reason {
+				t.Errorf("ParseArgs(%q) = %q, %q, want %q, %q", tt.text, to, reason, tt.to, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package kudos
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/queue"
+	"github.com/webuild-community/core/service/rule"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type slackSvc struct {
+	logger      *zap.Logger
+	db          *gorm.DB
+	slackClient *slack.Client
+	queueSvc    queue.Service
+	cfg         Config
+}
+
+// NewSlackService --
+func NewSlackService(logger *zap.Logger, db *gorm.DB, slackClient *slack.Client, queueSvc queue.Service, cfg Config) Service {
+	return &slackSvc{
+		logger:      logger,
+		db:          db,
+		slackClient: slackClient,
+		queueSvc:    queueSvc,
+		cfg:         cfg,
+	}
+}
+
+func (s *slackSvc) Give(key, fromUserID, toUserID, channelID, reason string) (*model.Kudos, error) {
+	if fromUserID == toUserID {
+		return nil, ErrSelfKudos
+	}
+
+	sUser, err := s.slackClient.GetUserInfo(toUserID)
+	if err != nil {
+		s.logger.Error("cannot get slack user info", zap.Error(err), zap.String("user_id", toUserID))
+		return nil, err
+	}
+	if sUser.IsBot {
+		return nil, ErrBotKudos
+	}
+
+	kudos := model.Kudos{
+		FromUserID:     fromUserID,
+		ToUserID:       toUserID,
+		ChannelID:      channelID,
+		Reason:         reason,
+		Exp:            s.cfg.Exp,
+		IdempotencyKey: fmt.Sprintf("kudos:%s:%s", fromUserID, key),
+	}
+	created := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// serialize the budget check of one giver
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "kudos:"+fromUserID).Error; err != nil {
+			return err
+		}
+
+		existing := model.Kudos{}
+		err := tx.Where("idempotency_key = ?", kudos.IdempotencyKey).Limit(1).Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			kudos = existing
+			return nil
+		}
+
+		if s.cfg.WeeklyBudget > 0 {
+			var given int64
+			if err := tx.Model(&model.Kudos{}).
+				Where("from_user_id = ? AND created_at >= ?", fromUserID, startOfWeek(time.Now())).
+				Count(&given).Error; err != nil {
+				return err
+			}
+			if given >= s.cfg.WeeklyBudget {
+				return ErrBudgetExhausted
+			}
+		}
+
+		if err := tx.Create(&kudos).Error; err != nil {
+			return err
+		}
+		created = true
+
+		// queued in the same transaction, the kudos and its exp are committed or rolled back together
+		return s.queueSvc.WithTx(tx).Add(&model.ExpEvent{
+			UserID:    toUserID,
+			EventType: model.ExpEventKudos,
+			Role:      string(rule.RoleReceiver),
+			ChannelID: channelID,
+			Delta:     kudos.Exp,
+			Rule:      fmt.Sprintf("kudos:%s", fromUserID),
+			CreatedAt: kudos.CreatedAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return &kudos, nil
+	}
+
+	shoutOutChannel := s.cfg.Channel
+	if shoutOutChannel == "" {
+		shoutOutChannel = channelID
+	}
+	if _, _, err := s.slackClient.PostMessage(shoutOutChannel, slack.MsgOptionText(
+		fmt.Sprintf(":tada: <@%s> gave kudos to <@%s> for %s", fromUserID, toUserID, reason),
+		false,
+	)); err != nil {
+		s.logger.Error("cannot post kudos shout-out", zap.Error(err), zap.String("channel_id", shoutOutChannel))
+	}
+
+	return &kudos, nil
+}
+
+// startOfWeek returns monday midnight of the week t is in
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
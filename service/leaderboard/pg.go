@@ -0,0 +1,122 @@
+package leaderboard
+
+import (
+	"errors"
+
+	"github.com/webuild-community/core/model"
+	"gorm.io/gorm"
+)
+
+type pg struct {
+	db *gorm.DB
+	// ranking makes all-time boards use the decayed ranking score instead of exp history
+	ranking bool
+}
+
+// NewPGService --
+func NewPGService(db *gorm.DB, ranking bool) Service {
+	return &pg{db: db, ranking: ranking}
+}
+
+type row struct {
+	UserID string
+	Score  int64
+	Rank   int
+}
+
+func (s *pg) Top(f Filter, limit int) ([]Entry, error) {
+	standings, err := s.standings(f)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []row{}
+	if err := s.db.Table("(?) AS standings", standings).
+		Order("rank, user_id").Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return s.entries(rows)
+}
+
+func (s *pg) Rank(f Filter, userID string) (*Entry, error) {
+	standings, err := s.standings(f)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []row{}
+	if err := s.db.Table("(?) AS standings", standings).
+		Where("user_id = ?", userID).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	entries, err := s.entries(rows)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+func (s *pg) standings(f Filter) (*gorm.DB, error) {
+	if s.ranking && f == (Filter{}) {
+		return s.db.Model(&model.User{}).
+			Select("id AS user_id, ranking_score AS score, RANK() OVER (ORDER BY ranking_score DESC) AS rank").
+			Where("ranking_score > 0"), nil
+	}
+
+	if f.Season {
+		season := model.Season{}
+		if err := s.db.Where("ended_at IS NULL").Order("started_at DESC").First(&season).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrNoActiveSeason
+			}
+			return nil, err
+		}
+		if season.StartedAt.After(f.From) {
+			f.From = season.StartedAt
+		}
+	}
+
+	q := s.db.Model(&model.ExpEvent{}).
+		Select("user_id, SUM(delta) AS score, RANK() OVER (ORDER BY SUM(delta) DESC) AS rank").
+		Group("user_id")
+	if !f.From.IsZero() {
+		// opening balances carry lifetime exp, they only count towards all-time boards
+		q = q.Where("created_at >= ? AND event_type <> ?", f.From, model.ExpEventOpening)
+	}
+	if f.ChannelID != "" {
+		q = q.Where("channel_id = ?", f.ChannelID)
+	}
+	return q, nil
+}
+
+func (s *pg) entries(rows []row) ([]Entry, error) {
+	ids := make([]string, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.UserID)
+	}
+	users := []model.User{}
+	if err := s.db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	entries := make([]Entry, 0, len(rows))
+	for _, r := range rows {
+		u := byID[r.UserID]
+		entries = append(entries, Entry{
+			UserID:        r.UserID,
+			Rank:          r.Rank,
+			Score:         r.Score,
+			DisplayName:   u.DisplayName,
+			ImageOriginal: u.ImageOriginal,
+			Level:         u.Level,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,62 @@
+package leaderboard
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webuild-community/core/model"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRun returns a db that builds statements without a server
+func dryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestStandingsSkipOpeningBalances(t *testing.T) {
+	now := time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		filter  Filter
+		opening bool
+	}{
+		{name: "all time", filter: Filter{}, opening: true},
+		{name: "all time in a channel", filter: Filter{ChannelID: "C0123"}, opening: true},
+		{name: "last 7 days", filter: Filter{From: now.AddDate(0, 0, -7)}},
+		{name: "last 24 hours in a channel", filter: Filter{From: now.Add(-24 * time.Hour), ChannelID: "C0123"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &pg{db: dryRun(t)}
+			q, err := s.standings(tt.filter)
+			if err != nil {
+				t.Fatalf("standings() error = %v", err)
+			}
+			stmt := q.Find(&[]row{}).Statement
+			skipped := strings.Contains(stmt.SQL.String(), "event_type <>")
+			if skipped == tt.opening {
+				t.Errorf("opening balances counted = %v, want %v: %s", !skipped, tt.opening, stmt.SQL.String())
+			}
+			if skipped && !containsVar(stmt.Vars, model.ExpEventOpening) {
+				t.Errorf("opening event type is not bound: %v", stmt.Vars)
+			}
+		})
+	}
+}
+
+func containsVar(vars []interface{}, v interface{}) bool {
+	for _, x := range vars {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
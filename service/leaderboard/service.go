@@ -0,0 +1,66 @@
+package leaderboard
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidFilter  = errors.New("usage: `$top [today|week|month|season] [#channel]`")
+	ErrNoActiveSeason = errors.New("no season is running")
+)
+
+var channelRe = regexp.MustCompile(`^<#([A-Z0-9]+)(\|([^>]*))?>$`)
+
+// Filter selects the exp events a leaderboard is computed from, zero values mean no restriction
+type Filter struct {
+	From      time.Time
+	ChannelID string
+	// Season restricts events to the running season
+	Season bool
+}
+
+type Entry struct {
+	UserID        string
+	Rank          int
+	Score         int64
+	DisplayName   string
+	ImageOriginal string
+	Level         uint
+}
+
+type Service interface {
+	Top(f Filter, limit int) ([]Entry, error)
+	// Rank returns the standing of one user, nil when they have no score for the filter
+	Rank(f Filter, userID string) (*Entry, error)
+}
+
+// ParseFilter reads `$top` arguments into a filter and a title describing it
+func ParseFilter(args string, now time.Time) (Filter, string, error) {
+	f := Filter{}
+	period := "All time"
+	where := ""
+	for _, arg := range strings.Fields(args) {
+		switch arg {
+		case "all":
+		case "today":
+			f.From, period = now.Add(-24*time.Hour), "Last 24 hours"
+		case "week":
+			f.From, period = now.AddDate(0, 0, -7), "Last 7 days"
+		case "month":
+			f.From, period = now.AddDate(0, 0, -30), "Last 30 days"
+		case "season":
+			f.Season, period = true, "This season"
+		default:
+			m := channelRe.FindStringSubmatch(arg)
+			if m == nil {
+				return Filter{}, "", ErrInvalidFilter
+			}
+			f.ChannelID = m[1]
+			where = " in <#" + m[1] + ">"
+		}
+	}
+	return f, period + where, nil
+}
@@ -0,0 +1,49 @@
+package leaderboard
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	now := time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		args    string
+		want    Filter
+		title   string
+		wantErr bool
+	}{
+		{args: "", want: Filter{}, title: "All time"},
+		{args: "all", want: Filter{}, title: "All time"},
+		{args: "today", want: Filter{From: now.Add(-24 * time.Hour)}, title: "Last 24 hours"},
+		{args: "week", want: Filter{From: now.AddDate(0, 0, -7)}, title: "Last 7 days"},
+		{args: "month", want: Filter{From: now.AddDate(0, 0, -30)}, title: "Last 30 days"},
+		{args: "season", want: Filter{Season: true}, title: "This season"},
+		{args: "<#C0123|general>", want: Filter{ChannelID: "C0123"}, title: "All time in <#C0123>"},
+		{args: "<#C0123>", want: Filter{ChannelID: "C0123"}, title: "All time in <#C0123>"},
+		{args: "week  <#C0123|general>", want: Filter{From: now.AddDate(0, 0, -7), ChannelID: "C0123"}, title: "Last 7 days in <#C0123>"},
+		{args: "<#C0123|general> season", want: Filter{Season: true, ChannelID: "C0123"}, title: "This season in <#C0123>"},
+		{args: "year", wantErr: true},
+		{args: "#general", wantErr: true},
+		{args: "week <@U0123>", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			f, title, err := ParseFilter(tt.args, now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFilter) {
+					t.Errorf("ParseFilter(%q) error = %v, want ErrInvalidFilter", tt.args, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) error = %v", tt.args, err)
+			}
+			if f != tt.want || title != tt.title {
+				t.Errorf("ParseFilter(%q) = %+v, %q, want %+v, %q", tt.args, f, title, tt.want, tt.title)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package level
+
+type linear struct {
+	step int64
+}
+
+// NewLinear needs step exp per level, step 100 is the historical curve
+func NewLinear(step int64) Service {
+	return &linear{step: step}
+}
+
+func (c *linear) Level(exp int64) uint {
+	if exp < 0 {
+		return 1
+	}
+	return uint(exp/c.step) + 1
+}
+
+func (c *linear) MinExp(level uint) int64 {
+	if level <= 1 {
+		return 0
+	}
+	return int64(level-1) * c.step
+}
@@ -0,0 +1,35 @@
+package level
+
+import "math"
+
+type quadratic struct {
+	base int64
+}
+
+// NewQuadratic needs base*(level-1)^2 exp to reach a level
+func NewQuadratic(base int64) Service {
+	return &quadratic{base: base}
+}
+
+func (c *quadratic) Level(exp int64) uint {
+	if exp < 0 {
+		return 1
+	}
+	l := uint(math.Sqrt(float64(exp)/float64(c.base))) + 1
+	// correct float rounding around exact squares
+	for l > 1 && c.MinExp(l) > exp {
+		l--
+	}
+	for c.MinExp(l+1) <= exp {
+		l++
+	}
+	return l
+}
+
+func (c *quadratic) MinExp(level uint) int64 {
+	if level <= 1 {
+		return 0
+	}
+	n := int64(level - 1)
+	return c.base * n * n
+}
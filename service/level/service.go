@@ -0,0 +1,51 @@
+package level
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Service maps total exp to a level, levels start at 1
+type Service interface {
+	Level(exp int64) uint
+	// MinExp is the exp needed to reach level
+	MinExp(level uint) int64
+}
+
+// ToNext returns the exp still needed to reach the next level
+func ToNext(s Service, exp int64) int64 {
+	return s.MinExp(s.Level(exp)+1) - exp
+}
+
+// NewFromEnv builds the curve named by LEVEL_CURVE (linear, quadratic or table)
+func NewFromEnv() (Service, error) {
+	step := int64(100)
+	if v := os.Getenv("LEVEL_STEP"); v != "" {
+		var err error
+		if step, err = strconv.ParseInt(v, 10, 64); err != nil || step <= 0 {
+			return nil, fmt.Errorf("invalid LEVEL_STEP %q", v)
+		}
+	}
+
+	switch os.Getenv("LEVEL_CURVE") {
+	case "", "linear":
+		return NewLinear(step), nil
+	case "quadratic":
+		return NewQuadratic(step), nil
+	case "table":
+		thresholds := []int64{}
+		for _, v := range strings.Split(os.Getenv("LEVEL_TABLE"), ",") {
+			t, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid LEVEL_TABLE entry %q", v)
+			}
+			thresholds = append(thresholds, t)
+		}
+		return NewTable(thresholds)
+	}
+
+	return nil, errors.New("unknown LEVEL_CURVE " + os.Getenv("LEVEL_CURVE"))
+}
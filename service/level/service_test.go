@@ -0,0 +1,168 @@
+package level
+
+import (
+	"os"
+	"testing"
+)
+
+func mustTable(t *testing.T, thresholds ...int64) Service {
+	t.Helper()
+	c, err := NewTable(thresholds)
+	if err != nil {
+		t.Fatalf("NewTable(%v) error = %v", thresholds, err)
+	}
+	return c
+}
+
+func TestLevel(t *testing.T) {
+	table := mustTable(t, 50, 150, 300)
+	tests := []struct {
+		name  string
+		curve Service
+		exp   int64
+		want  uint
+	}{
+		{"linear negative", NewLinear(100), -5, 1},
+		{"linear zero", NewLinear(100), 0, 1},
+		{"linear just below a level", NewLinear(100), 99, 1},
+		{"linear on a level", NewLinear(100), 100, 2},
+		{"linear far", NewLinear(100), 1050, 11},
+		{"quadratic negative", NewQuadratic(100), -1, 1},
+		{"quadratic just below a square", NewQuadratic(100), 399, 2},
+		{"quadratic on a square", NewQuadratic(100), 400, 3},
+		{"quadratic large square", NewQuadratic(1), 999999 * 999999, 1000000},
+		{"quadratic below a large square", NewQuadratic(1), 999999*999999 - 1, 999999},
+		{"table before the first threshold", table, 49, 1},
+		{"table on the first threshold", table, 50, 2},
+		{"table between thresholds", table, 200, 3},
+		{"table on the last threshold", table, 300, 4},
+		{"table past the end keeps the last gap", table, 449, 4},
+		{"table one gap past the end", table, 450, 5},
+		{"table negative", table, -10, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.curve.Level(tt.exp); got != tt.want {
+				t.Errorf("Level(%d) = %d, want %d", tt.exp, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinExpMatchesLevel(t *testing.T) {
+	curves := map[string]Service{
+		"linear":    NewLinear(100),
+		"quadratic": NewQuadratic(75),
+		"table":     mustTable(t, 10, 30, 70, 150),
+	}
+
+	for name, c := range curves {
+		t.Run(name, func(t *testing.T) {
+			if got := c.MinExp(1); got != 0 {
+				t.Errorf("MinExp(1) = %d, want 0", got)
+			}
+			for l := uint(2); l <= 20; l++ {
+				min := c.MinExp(l)
+				if got := c.Level(min); got != l {
+					t.Errorf("Level(MinExp(%d)) = %d", l, got)
+				}
+				if got := c.Level(min - 1); got != l-1 {
+					t.Errorf("Level(MinExp(%d)-1) = %d, want %d", l, got, l-1)
+				}
+			}
+		})
+	}
+}
+
+func TestToNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve Service
+		exp   int64
+		want  int64
+	}{
+		{"linear start", NewLinear(100), 0, 100},
+		{"linear midway", NewLinear(100), 130, 70},
+		{"quadratic", NewQuadratic(100), 150, 250},
+		{"table past the end", mustTable(t, 50, 150, 300), 310, 140},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToNext(tt.curve, tt.exp); got != tt.want {
+				t.Errorf("ToNext(%d) = %d, want %d", tt.exp, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTableRejects(t *testing.T) {
+	tests := map[string][]int64{
+		"empty":          nil,
+		"single":         {100},
+		"equal":          {100, 100},
+		"not increasing": {100, 300, 200},
+	}
+
+	for name, thresholds := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewTable(thresholds); err == nil {
+				t.Errorf("NewTable(%v) returned no error", thresholds)
+			}
+		})
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		exp     int64
+		want    uint
+		wantErr bool
+	}{
+		{name: "linear by default", env: map[string]string{}, exp: 250, want: 3},
+		{name: "linear step", env: map[string]string{"LEVEL_STEP": "50"}, exp: 250, want: 6},
+		{name: "quadratic", env: map[string]string{"LEVEL_CURVE": "quadratic"}, exp: 400, want: 3},
+		{name: "table", env: map[string]string{"LEVEL_CURVE": "table", "LEVEL_TABLE": "10, 30, 70"}, exp: 30, want: 3},
+		{name: "invalid step", env: map[string]string{"LEVEL_STEP": "0"}, wantErr: true},
+		{name: "invalid table", env: map[string]string{"LEVEL_CURVE": "table", "LEVEL_TABLE": "10,abc"}, wantErr: true},
+		{name: "unknown curve", env: map[string]string{"LEVEL_CURVE": "cubic"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"LEVEL_CURVE", "LEVEL_STEP", "LEVEL_TABLE"} {
+				setenv(t, k, tt.env[k])
+			}
+
+			c, err := NewFromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("NewFromEnv() returned no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewFromEnv() error = %v", err)
+			}
+			if got := c.Level(tt.exp); got != tt.want {
+				t.Errorf("Level(%d) = %d, want %d", tt.exp, got, tt.want)
+			}
+		})
+	}
+}
+
+// setenv sets key for the duration of the test, go 1.16 has no t.Setenv
+func setenv(t *testing.T, key, value string) {
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package level
+
+import (
+	"errors"
+	"sort"
+)
+
+type table struct {
+	thresholds []int64
+}
+
+// NewTable reads thresholds[i] as the exp needed for level i+2, levels past the end of
+// the table keep the gap of its last two entries
+func NewTable(thresholds []int64) (Service, error) {
+	if len(thresholds) < 2 {
+		return nil, errors.New("level table needs at least two thresholds")
+	}
+	for i := 1; i < len(thresholds); i++ {
+		if thresholds[i] <= thresholds[i-1] {
+			return nil, errors.New("level table thresholds must be increasing")
+		}
+	}
+	return &table{thresholds: thresholds}, nil
+}
+
+func (c *table) Level(exp int64) uint {
+	last := c.thresholds[len(c.thresholds)-1]
+	if exp >= last {
+		gap := last - c.thresholds[len(c.thresholds)-2]
+		return uint(len(c.thresholds)+1) + uint((exp-last)/gap)
+	}
+	return uint(sort.Search(len(c.thresholds), func(i int) bool { return c.thresholds[i] > exp })) + 1
+}
+
+func (c *table) MinExp(level uint) int64 {
+	if level <= 1 {
+		return 0
+	}
+	i := int(level) - 2
+	if i < len(c.thresholds) {
+		return c.thresholds[i]
+	}
+	n := len(c.thresholds)
+	gap := c.thresholds[n-1] - c.thresholds[n-2]
+	return c.thresholds[n-1] + int64(i-n+1)*gap
+}
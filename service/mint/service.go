@@ -0,0 +1,34 @@
+package mint
+
+import (
+	"os"
+	"strconv"
+)
+
+// Rules configures how much RDF is minted, a zero value disables the rule
+type Rules struct {
+	LevelUpReward   float64
+	MilestoneExp    int64
+	MilestoneReward float64
+	WeeklyTopSize   int
+	WeeklyTopReward float64
+}
+
+// NewRulesFromEnv reads minting rules from MINT_* environment variables
+func NewRulesFromEnv() Rules {
+	r := Rules{WeeklyTopSize: 10}
+	r.LevelUpReward, _ = strconv.ParseFloat(os.Getenv("MINT_LEVEL_UP_REWARD"), 64)
+	r.MilestoneExp, _ = strconv.ParseInt(os.Getenv("MINT_MILESTONE_EXP"), 10, 64)
+	r.MilestoneReward, _ = strconv.ParseFloat(os.Getenv("MINT_MILESTONE_REWARD"), 64)
+	r.WeeklyTopReward, _ = strconv.ParseFloat(os.Getenv("MINT_WEEKLY_TOP_REWARD"), 64)
+	if size, err := strconv.Atoi(os.Getenv("MINT_WEEKLY_TOP_SIZE")); err == nil {
+		r.WeeklyTopSize = size
+	}
+	return r
+}
+
+type Service interface {
+	// OnExpChange mints rewards for levels and milestones crossed by an exp change
+	OnExpChange(userID string, oldExp, newExp int64, oldLevel, newLevel uint, channelID string) error
+	WeeklyBonus() error
+}
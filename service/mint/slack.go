@@ -0,0 +1,117 @@
+package mint
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/leaderboard"
+	"github.com/webuild-community/core/service/slackutil"
+	"github.com/webuild-community/core/service/transaction"
+	"go.uber.org/zap"
+)
+
+type slackSvc struct {
+	logger         *zap.Logger
+	slackClient    *slack.Client
+	ledger         transaction.Service
+	leaderboardSvc leaderboard.Service
+	rules          Rules
+}
+
+// NewSlackService --
+func NewSlackService(logger *zap.Logger, slackClient *slack.Client, ledger transaction.Service, leaderboardSvc leaderboard.Service, rules Rules) Service {
+	return &slackSvc{
+		logger:         logger,
+		slackClient:    slackClient,
+		ledger:         ledger,
+		leaderboardSvc: leaderboardSvc,
+		rules:          rules,
+	}
+}
+
+func (s *slackSvc) OnExpChange(userID string, oldExp, newExp int64, oldLevel, newLevel uint, channelID string) error {
+	var minted float64
+	reasons := []string{}
+
+	if s.rules.LevelUpReward > 0 {
+		for level := oldLevel + 1; level <= newLevel; level++ {
+			// keyed by level so dropping a level and climbing back does not mint twice
+			if _, err := s.ledger.Credit(userID, s.rules.LevelUpReward, transaction.Entry{
+				Reason:         model.ReasonLevelUp,
+				IdempotencyKey: fmt.Sprintf("mint:level:%s:%d", userID, level),
+				Memo:           fmt.Sprintf("reached level %d", level),
+			}); errors.Is(err, transaction.ErrIdempotencyMismatch) {
+				// minted before, under a reward that has changed since
+				continue
+			} else if err != nil {
+				return err
+			}
+			minted += s.rules.LevelUpReward
+			reasons = append(reasons, fmt.Sprintf("reaching level %d", level))
+		}
+	}
+
+	if s.rules.MilestoneExp > 0 && s.rules.MilestoneReward > 0 && newExp > oldExp {
+		for m := (oldExp/s.rules.MilestoneExp + 1) * s.rules.MilestoneExp; m <= newExp; m += s.rules.MilestoneExp {
+			if m <= 0 {
+				continue
+			}
+			if _, err := s.ledger.Credit(userID, s.rules.MilestoneReward, transaction.Entry{
+				Reason:         model.ReasonMilestone,
+				IdempotencyKey: fmt.Sprintf("mint:milestone:%s:%d", userID, m),
+				Memo:           fmt.Sprintf("reached %d exp", m),
+			}); errors.Is(err, transaction.ErrIdempotencyMismatch) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			minted += s.rules.MilestoneReward
+			reasons = append(reasons, fmt.Sprintf("reaching %d exp", m))
+		}
+	}
+
+	if minted == 0 || channelID == "" {
+		return nil
+	}
+
+	text := fmt.Sprintf("<@%s> earned `%v` RDF for %s :tada:", userID, minted, strings.Join(reasons, ", "))
+	_, _, err := s.slackClient.PostMessage(channelID, slack.MsgOptionText(text, false))
+	return err
+}
+
+func (s *slackSvc) WeeklyBonus() error {
+	if s.rules.WeeklyTopReward <= 0 || s.rules.WeeklyTopSize <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	entries, err := s.leaderboardSvc.Top(leaderboard.Filter{From: now.AddDate(0, 0, -7)}, s.rules.WeeklyTopSize)
+	if err != nil {
+		return err
+	}
+
+	year, week := now.ISOWeek()
+	for _, e := range entries {
+		if e.Score <= 0 {
+			continue
+		}
+		if _, err := s.ledger.Credit(e.UserID, s.rules.WeeklyTopReward, transaction.Entry{
+			Reason:         model.ReasonWeeklyTop,
+			IdempotencyKey: fmt.Sprintf("mint:weekly:%d-%d:%s", year, week, e.UserID),
+			Memo:           fmt.Sprintf("top %d of week %d-%d", e.Rank, year, week),
+		}); err != nil {
+			return err
+		}
+
+		slackutil.DM(s.slackClient, s.logger, e.UserID, slack.MsgOptionText(
+			fmt.Sprintf("*Weekly bonus*\nYou ranked #%d this week and earned `%v` RDF", e.Rank, s.rules.WeeklyTopReward),
+			false,
+		))
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package notification
+
+import (
+	"os"
+	"time"
+)
+
+// Config customises level notifications, {user} and {level} are replaced in texts
+type Config struct {
+	Channel     string
+	LevelUpText string
+	DMText      string
+	Throttle    time.Duration
+}
+
+// NewConfigFromEnv reads NOTIFY_* environment variables
+func NewConfigFromEnv() Config {
+	c := Config{
+		Channel:     os.Getenv("NOTIFY_CHANNEL"),
+		LevelUpText: ":tada: <@{user}> reached *level {level}*!",
+		DMText:      "You are now *level {level}*, keep it up!",
+		Throttle:    time.Hour,
+	}
+	if v := os.Getenv("NOTIFY_LEVEL_UP_TEXT"); v != "" {
+		c.LevelUpText = v
+	}
+	if v := os.Getenv("NOTIFY_DM_TEXT"); v != "" {
+		c.DMText = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("NOTIFY_THROTTLE")); err == nil {
+		c.Throttle = v
+	}
+	return c
+}
+
+type Service interface {
+	// LevelChanged announces a level-up in channelID (or the configured channel) and DMs the user
+	LevelChanged(userID string, oldLevel, newLevel uint, channelID string) error
+	SetOptOut(userID string, optOut bool) error
+}
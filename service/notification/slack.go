@@ -0,0 +1,81 @@
+package notification
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/slackutil"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type slackSvc struct {
+	logger      *zap.Logger
+	db          *gorm.DB
+	slackClient *slack.Client
+	config      Config
+}
+
+// NewSlackService --
+func NewSlackService(logger *zap.Logger, db *gorm.DB, slackClient *slack.Client, config Config) Service {
+	return &slackSvc{
+		logger:      logger,
+		db:          db,
+		slackClient: slackClient,
+		config:      config,
+	}
+}
+
+func (s *slackSvc) LevelChanged(userID string, oldLevel, newLevel uint, channelID string) error {
+	if oldLevel == newLevel {
+		return nil
+	}
+
+	user := model.User{}
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+	if user.NotificationsOptOut {
+		return nil
+	}
+	if user.LevelNotifiedAt != nil && time.Since(*user.LevelNotifiedAt) < s.config.Throttle {
+		return nil
+	}
+
+	if err := s.db.Model(&user).Update("level_notified_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	r := strings.NewReplacer("{user}", userID, "{level}", strconv.Itoa(int(newLevel)))
+	// level-downs are only told to the user
+	if newLevel > oldLevel {
+		if s.config.Channel != "" {
+			channelID = s.config.Channel
+		}
+		if channelID != "" {
+			var accessory *slack.Accessory
+			if user.ImageOriginal != "" {
+				accessory = slack.NewAccessory(slack.NewImageBlockElement(user.ImageOriginal, user.DisplayName))
+			}
+			section := slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", r.Replace(s.config.LevelUpText), false, false), nil, accessory)
+			if _, _, err := s.slackClient.PostMessage(channelID, slack.MsgOptionBlocks(section)); err != nil {
+				s.logger.Error("cannot announce level up", zap.Error(err), zap.String("user_id", userID))
+			}
+		}
+	}
+
+	text := r.Replace(s.config.DMText)
+	if newLevel < oldLevel {
+		text = r.Replace("You dropped to *level {level}*")
+	}
+	text += "\n_Type `$notify off` to stop these messages_"
+	section := slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil)
+	return slackutil.DM(s.slackClient, s.logger, userID, slack.MsgOptionBlocks(section))
+}
+
+func (s *slackSvc) SetOptOut(userID string, optOut bool) error {
+	return s.db.Model(&model.User{}).Where("id = ?", userID).Update("notifications_opt_out", optOut).Error
+}
@@ -0,0 +1,53 @@
+package order
+
+import (
+	"errors"
+
+	"github.com/webuild-community/core/model"
+)
+
+var (
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrInvalidTransition = errors.New("the order cannot move to this status")
+)
+
+// action ids of the order buttons
+const (
+	ActionApprove  = "order_approve"
+	ActionReject   = "order_reject"
+	ActionFulfil   = "order_fulfil"
+	ActionRefund   = "order_refund"
+	ActionShipping = "order_shipping"
+
+	// ShippingCallbackID identifies the shipping details modal
+	ShippingCallbackID = "order_shipping"
+	ShippingBlockID    = "shipping"
+	ShippingActionID   = "details"
+)
+
+// Actions maps admin buttons to the status they move an order to
+var Actions = map[string]model.OrderStatus{
+	ActionApprove: model.OrderApproved,
+	ActionReject:  model.OrderRejected,
+	ActionFulfil:  model.OrderFulfilled,
+	ActionRefund:  model.OrderRefunded,
+}
+
+// transitions lists the statuses an order can move to a status from
+var transitions = map[model.OrderStatus][]model.OrderStatus{
+	model.OrderApproved:  {model.OrderPending},
+	model.OrderFulfilled: {model.OrderApproved},
+	model.OrderRejected:  {model.OrderPending},
+	model.OrderRefunded:  {model.OrderApproved},
+}
+
+type Service interface {
+	// Placed announces a new order to the fulfilment channel and its buyer
+	Placed(order *model.Transaction) error
+	// Transition moves an order to status on behalf of an admin, rejected and refunded orders
+	// are paid back and their item restocked
+	Transition(orderID uint, adminID string, status model.OrderStatus) (*model.Transaction, error)
+	// OpenShippingForm shows the shipping details modal to the buyer
+	OpenShippingForm(triggerID string, orderID uint, userID string) error
+	SetShipping(orderID uint, userID, details string) error
+}
@@ -0,0 +1,249 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/slackutil"
+	"github.com/webuild-community/core/service/transaction"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type slackSvc struct {
+	logger      *zap.Logger
+	db          *gorm.DB
+	slackClient *slack.Client
+	ledger      transaction.Service
+	channel     string
+}
+
+// NewSlackService --
+func NewSlackService(logger *zap.Logger, db *gorm.DB, slackClient *slack.Client, ledger transaction.Service, channel string) Service {
+	return &slackSvc{
+		logger:      logger,
+		db:          db,
+		slackClient: slackClient,
+		ledger:      ledger,
+		channel:     channel,
+	}
+}
+
+func (s *slackSvc) Placed(order *model.Transaction) error {
+	item := s.item(order.ItemID)
+
+	slackutil.DM(s.slackClient, s.logger, order.UserID, slack.MsgOptionBlocks(
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType,
+			fmt.Sprintf("Your order #%d for *%s* is waiting for review. Please add your shipping details so we can send it to you", order.ID, item.Name),
+			false, false), nil, nil),
+		slack.NewActionBlock("", shippingButton(order.ID)),
+	))
+
+	if s.channel == "" {
+		// without a fulfilment channel every admin gets the order
+		admins := []model.User{}
+		if err := s.db.Where("is_admin = ?", true).Find(&admins).Error; err != nil {
+			return err
+		}
+		for _, admin := range admins {
+			slackutil.DM(s.slackClient, s.logger, admin.ID, slack.MsgOptionBlocks(adminBlocks(order, item)...))
+		}
+		return nil
+	}
+
+	channelID, ts, err := s.slackClient.PostMessage(s.channel, slack.MsgOptionBlocks(adminBlocks(order, item)...))
+	if err != nil {
+		return err
+	}
+	return s.db.Model(order).Updates(map[string]interface{}{
+		"fulfilment_channel_id": channelID,
+		"fulfilment_ts":         ts,
+	}).Error
+}
+
+func (s *slackSvc) Transition(orderID uint, adminID string, status model.OrderStatus) (*model.Transaction, error) {
+	order := model.Transaction{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+		if !canMove(order.Status, status) {
+			return ErrInvalidTransition
+		}
+
+		if status == model.OrderRejected || status == model.OrderRefunded {
+			if order.JournalEntryID != nil {
+				if _, err := s.ledger.WithTx(tx).Reverse(*order.JournalEntryID, transaction.Entry{
+					IdempotencyKey: fmt.Sprintf("refund:%d", order.ID),
+					Memo:           fmt.Sprintf("order #%d %s", order.ID, status),
+				}); err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&model.Item{}).Where("id = ? AND redeemed > 0", order.ItemID).
+				Update("redeemed", gorm.Expr("redeemed - 1")).Error; err != nil {
+				return err
+			}
+		}
+
+		order.Status = status
+		order.HandledBy = adminID
+		return tx.Model(&order).Updates(map[string]interface{}{"status": status, "handled_by": adminID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	item := s.item(order.ItemID)
+	s.refresh(&order, item)
+	slackutil.DM(s.slackClient, s.logger, order.UserID, slack.MsgOptionText(buyerText(&order, item), false))
+	return &order, nil
+}
+
+func (s *slackSvc) OpenShippingForm(triggerID string, orderID uint, userID string) error {
+	order := model.Transaction{}
+	if err := s.db.First(&order, "id = ? AND user_id = ?", orderID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrderNotFound
+		}
+		return err
+	}
+
+	input := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "Name, address and phone number", false, false),
+		ShippingActionID,
+	)
+	input.Multiline = true
+	input.InitialValue = order.ShippingDetails
+
+	_, err := s.slackClient.OpenView(triggerID, slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, fmt.Sprintf("Order #%d", order.ID), false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "Save", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		CallbackID:      ShippingCallbackID,
+		PrivateMetadata: strconv.FormatUint(uint64(order.ID), 10),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewInputBlock(ShippingBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Shipping details", false, false), input),
+		}},
+	})
+	return err
+}
+
+func (s *slackSvc) SetShipping(orderID uint, userID, details string) error {
+	res := s.db.Model(&model.Transaction{}).
+		Where("id = ? AND user_id = ? AND status IN ?", orderID, userID, []model.OrderStatus{model.OrderPending, model.OrderApproved}).
+		Update("shipping_details", details)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidTransition
+	}
+
+	order := model.Transaction{}
+	if err := s.db.First(&order, orderID).Error; err != nil {
+		return err
+	}
+	item := s.item(order.ItemID)
+	s.refresh(&order, item)
+	slackutil.DM(s.slackClient, s.logger, userID, slack.MsgOptionText(fmt.Sprintf("Thanks, the shipping details of order #%d for *%s* were saved", order.ID, item.Name), false))
+	return nil
+}
+
+// refresh redraws the order message in the fulfilment channel
+func (s *slackSvc) refresh(order *model.Transaction, item model.Item) {
+	if order.FulfilmentTS == "" {
+		return
+	}
+	if _, _, _, err := s.slackClient.UpdateMessage(order.FulfilmentChannelID, order.FulfilmentTS,
+		slack.MsgOptionBlocks(adminBlocks(order, item)...),
+	); err != nil {
+		s.logger.Error("cannot update order message", zap.Error(err), zap.Uint("order_id", order.ID))
+	}
+}
+
+// item loads the ordered item, expired and deleted ones included
+func (s *slackSvc) item(id string) model.Item {
+	item := model.Item{ID: id, Name: id}
+	if err := s.db.First(&item, "id = ?", id).Error; err != nil {
+		s.logger.Error("cannot find ordered item", zap.Error(err), zap.String("item_id", id))
+	}
+	return item
+}
+
+func canMove(from, to model.OrderStatus) bool {
+	for _, s := range transitions[to] {
+		if s == from {
+			return true
+		}
+	}
+	return false
+}
+
+func adminBlocks(order *model.Transaction, item model.Item) []slack.Block {
+	shipping := "_not provided yet_"
+	if order.ShippingDetails != "" {
+		shipping = "\n>" + order.ShippingDetails
+	}
+	text := fmt.Sprintf("*Order #%d* <@%s> redeemed *%s* for `%v` RDF\nStatus: *%s*\nShipping details: %s",
+		order.ID, order.UserID, item.Name, order.Price, order.Status, shipping)
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+	}
+
+	switch order.Status {
+	case model.OrderPending:
+		blocks = append(blocks, slack.NewActionBlock("",
+			button(ActionApprove, "Approve", order.ID, "primary"),
+			button(ActionReject, "Reject", order.ID, "danger"),
+		))
+	case model.OrderApproved:
+		blocks = append(blocks, slack.NewActionBlock("",
+			button(ActionFulfil, "Mark fulfilled", order.ID, "primary"),
+			button(ActionRefund, "Refund", order.ID, "danger"),
+		))
+	}
+	if order.HandledBy != "" {
+		blocks = append(blocks, slack.NewContextBlock("",
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("Last handled by <@%s>", order.HandledBy), false, false),
+		))
+	}
+	return blocks
+}
+
+func buyerText(order *model.Transaction, item model.Item) string {
+	switch order.Status {
+	case model.OrderApproved:
+		text := fmt.Sprintf("Your order #%d for *%s* was approved and will be shipped soon", order.ID, item.Name)
+		if order.ShippingDetails == "" {
+			text += ", please add your shipping details from the order message"
+		}
+		return text
+	case model.OrderFulfilled:
+		return fmt.Sprintf("Your order #%d for *%s* was shipped, enjoy!", order.ID, item.Name)
+	case model.OrderRejected:
+		return fmt.Sprintf("Your order #%d for *%s* was rejected, `%v` RDF were refunded to your balance", order.ID, item.Name, order.Price)
+	case model.OrderRefunded:
+		return fmt.Sprintf("Your order #%d for *%s* was refunded, `%v` RDF were returned to your balance", order.ID, item.Name, order.Price)
+	}
+	return fmt.Sprintf("Your order #%d for *%s* is %s", order.ID, item.Name, order.Status)
+}
+
+func button(actionID, text string, orderID uint, style slack.Style) *slack.ButtonBlockElement {
+	b := slack.NewButtonBlockElement(actionID, strconv.FormatUint(uint64(orderID), 10),
+		slack.NewTextBlockObject(slack.PlainTextType, text, false, false))
+	b.Style = style
+	return b
+}
+
+func shippingButton(orderID uint) *slack.ButtonBlockElement {
+	return button(ActionShipping, "Add shipping details", orderID, "")
+}
@@ -0,0 +1,79 @@
+package quest
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/webuild-community/core/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidQuest  = errors.New("a quest needs a name, an event_type and a positive target")
+	ErrQuestNotFound = errors.New("quest not found")
+)
+
+var channelRe = regexp.MustCompile(`^<#([A-Z0-9]+)(\|[^>]*)?>$`)
+
+// Progress of a user on one quest
+type Progress struct {
+	Quest     model.Quest
+	Count     int64
+	Completed bool
+}
+
+type Service interface {
+	// WithTx returns a quest service whose progress and rewards join an outer db transaction
+	WithTx(tx *gorm.DB) Service
+	Add(q *model.Quest) error
+	// End closes a quest now, progress made afterwards is not counted
+	End(id uint) error
+	Active() ([]model.Quest, error)
+	Progress(userID string) ([]Progress, error)
+	// Track advances the user's active quests with their exp events, rewards the completed ones
+	// and returns them, the user is told through Announce once the progress is committed
+	Track(userID string, events []model.ExpEvent) ([]model.Quest, error)
+	// Announce tells the user about quests they completed
+	Announce(userID string, quests []model.Quest)
+	// GithubLinked completes the user's active github_linked quests
+	GithubLinked(userID string) error
+}
+
+// Parse reads a quest definition sent to `/quest add`
+func Parse(text string) (*model.Quest, error) {
+	q := &model.Quest{}
+	if err := json.Unmarshal([]byte(text), q); err != nil {
+		return nil, err
+	}
+
+	// slack escapes channels, accept them as typed
+	if m := channelRe.FindStringSubmatch(strings.TrimSpace(q.ChannelID)); m != nil {
+		q.ChannelID = m[1]
+	}
+	if q.Target == 0 {
+		q.Target = 1
+	}
+	return q, nil
+}
+
+// matches reports whether e counts towards q
+func matches(q model.Quest, e model.ExpEvent) bool {
+	if e.EventType != q.EventType || e.Delta < 0 {
+		return false
+	}
+	if q.Role != "" && e.Role != q.Role {
+		return false
+	}
+	if q.ChannelID != "" && e.ChannelID != q.ChannelID {
+		return false
+	}
+	if q.ThreadReply && !e.ThreadReply {
+		return false
+	}
+	if e.CreatedAt.Before(q.StartsAt) || (q.EndsAt != nil && !e.CreatedAt.Before(*q.EndsAt)) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,225 @@
+package quest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/queue"
+	"github.com/webuild-community/core/service/slackutil"
+	"github.com/webuild-community/core/service/transaction"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type slackSvc struct {
+	logger      *zap.Logger
+	db          *gorm.DB
+	slackClient *slack.Client
+	queueSvc    queue.Service
+	ledger      transaction.Service
+}
+
+// NewSlackService --
+func NewSlackService(logger *zap.Logger, db *gorm.DB, slackClient *slack.Client, queueSvc queue.Service, ledger transaction.Service) Service {
+	return &slackSvc{
+		logger:      logger,
+		db:          db,
+		slackClient: slackClient,
+		queueSvc:    queueSvc,
+		ledger:      ledger,
+	}
+}
+
+func (s *slackSvc) WithTx(tx *gorm.DB) Service {
+	return &slackSvc{
+		logger:      s.logger,
+		db:          tx,
+		slackClient: s.slackClient,
+		queueSvc:    s.queueSvc,
+		ledger:      s.ledger,
+	}
+}
+
+func (s *slackSvc) Add(q *model.Quest) error {
+	if q.Name == "" || q.EventType == "" || q.Target <= 0 {
+		return ErrInvalidQuest
+	}
+	if q.StartsAt.IsZero() {
+		q.StartsAt = time.Now()
+	}
+	return s.db.Create(q).Error
+}
+
+func (s *slackSvc) End(id uint) error {
+	res := s.db.Model(&model.Quest{}).
+		Where("id = ? AND (ends_at IS NULL OR ends_at > now())", id).
+		Update("ends_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrQuestNotFound
+	}
+	return nil
+}
+
+func (s *slackSvc) Active() ([]model.Quest, error) {
+	quests := []model.Quest{}
+	return quests, s.db.
+		Where("starts_at <= now() AND (ends_at IS NULL OR ends_at > now())").
+		Order("id").Find(&quests).Error
+}
+
+func (s *slackSvc) Progress(userID string) ([]Progress, error) {
+	quests, err := s.Active()
+	if err != nil {
+		return nil, err
+	}
+	if len(quests) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(quests))
+	for _, q := range quests {
+		ids = append(ids, q.ID)
+	}
+	rows := []model.QuestProgress{}
+	if err := s.db.Where("user_id = ? AND quest_id IN ?", userID, ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	byQuest := make(map[uint]model.QuestProgress, len(rows))
+	for _, r := range rows {
+		byQuest[r.QuestID] = r
+	}
+
+	res := make([]Progress, 0, len(quests))
+	for _, q := range quests {
+		r := byQuest[q.ID]
+		res = append(res, Progress{Quest: q, Count: r.Count, Completed: r.CompletedAt != nil})
+	}
+	return res, nil
+}
+
+func (s *slackSvc) Track(userID string, events []model.ExpEvent) ([]model.Quest, error) {
+	quests, err := s.Active()
+	if err != nil {
+		return nil, err
+	}
+
+	completed := []model.Quest{}
+	for _, q := range quests {
+		var n int64
+		for _, e := range events {
+			if matches(q, e) {
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		done, err := s.advance(q, userID, n)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			completed = append(completed, q)
+		}
+	}
+	return completed, nil
+}
+
+func (s *slackSvc) GithubLinked(userID string) error {
+	quests, err := s.Active()
+	if err != nil {
+		return err
+	}
+
+	completed := []model.Quest{}
+	for _, q := range quests {
+		if q.EventType != model.QuestGithubLinked {
+			continue
+		}
+		done, err := s.advance(q, userID, q.Target)
+		if err != nil {
+			return err
+		}
+		if done {
+			completed = append(completed, q)
+		}
+	}
+	s.Announce(userID, completed)
+	return nil
+}
+
+func (s *slackSvc) Announce(userID string, quests []model.Quest) {
+	for _, q := range quests {
+		slackutil.DM(s.slackClient, s.logger, userID, slack.MsgOptionText(
+			fmt.Sprintf(":trophy: Quest completed: *%s*%s", q.Name, rewardText(q)),
+			false,
+		))
+	}
+}
+
+// advance adds n to the user's progress on q, rewards the user and reports true when it reaches the target
+func (s *slackSvc) advance(q model.Quest, userID string, n int64) (bool, error) {
+	completed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		// completed quests are left untouched, no row is returned for them
+		if err := tx.Raw(`
+			INSERT INTO quest_progress (quest_id, user_id, count) VALUES (?, ?, ?)
+			ON CONFLICT (quest_id, user_id) DO UPDATE
+			SET count = quest_progress.count + EXCLUDED.count, updated_at = now()
+			WHERE quest_progress.completed_at IS NULL
+			RETURNING count`, q.ID, userID, n).
+			Scan(&count).Error; err != nil {
+			return err
+		}
+		if count < q.Target {
+			return nil
+		}
+
+		if err := tx.Model(&model.QuestProgress{}).
+			Where("quest_id = ? AND user_id = ?", q.ID, userID).
+			Update("completed_at", time.Now()).Error; err != nil {
+			return err
+		}
+		completed = true
+
+		if q.RewardRDF > 0 {
+			if _, err := s.ledger.WithTx(tx).Credit(userID, q.RewardRDF, transaction.Entry{
+				Reason:         model.ReasonQuest,
+				IdempotencyKey: fmt.Sprintf("quest:%d:%s", q.ID, userID),
+				Memo:           q.Name,
+			}); err != nil {
+				return err
+			}
+		}
+		if q.RewardExp > 0 {
+			// queued in the same transaction, a failed completion cannot leave its exp behind
+			return s.queueSvc.WithTx(tx).Add(&model.ExpEvent{
+				UserID:    userID,
+				EventType: model.ExpEventQuest,
+				Delta:     q.RewardExp,
+				Rule:      fmt.Sprintf("quest:%d", q.ID),
+				CreatedAt: time.Now(),
+			})
+		}
+		return nil
+	})
+	return completed && err == nil, err
+}
+
+func rewardText(q model.Quest) string {
+	switch {
+	case q.RewardExp > 0 && q.RewardRDF > 0:
+		return fmt.Sprintf(", you earned `%d` exp and `%v` RDF", q.RewardExp, q.RewardRDF)
+	case q.RewardExp > 0:
+		return fmt.Sprintf(", you earned `%d` exp", q.RewardExp)
+	case q.RewardRDF > 0:
+		return fmt.Sprintf(", you earned `%v` RDF", q.RewardRDF)
+	}
+	return ""
+}
@@ -2,38 +2,80 @@ package queue
 
 import (
 	"container/list"
+	"sync"
+
+	"gorm.io/gorm"
 )
 
 type queue struct {
-	isConsuming bool
+	mu          sync.Mutex
 	l           *list.List
+	nextID      uint
+	maxAttempts int
+	inflight    map[uint]*Message
+	deadLetters []Message
 }
 
-// NewQueueService --
+// NewQueueService returns an in-memory queue, pending messages are lost on restart
 func NewQueueService() Service {
-	return &queue{l: list.New()}
+	return &queue{
+		l:           list.New(),
+		maxAttempts: 5,
+		inflight:    map[uint]*Message{},
+	}
+}
+
+// WithTx returns the queue itself, the in-memory queue cannot join a db transaction: messages
+// added or acked inside one are kept when it rolls back, use the postgres queue where that matters
+func (q *queue) WithTx(tx *gorm.DB) Service {
+	return q
 }
 
 func (q *queue) Add(value interface{}) error {
-	q.l.PushFront(value)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.nextID++
+	q.l.PushBack(&Message{ID: q.nextID, Value: value})
 	return nil
 }
 
-func (q *queue) Consume() interface{} {
-	e := q.l.Front()
-	if e == nil {
-		return nil
-	}
+func (q *queue) Claim(limit int) ([]Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 
-	val := e.Value
-	q.l.Remove(e)
-	return val
+	msgs := []Message{}
+	for e := q.l.Front(); e != nil && len(msgs) < limit; e = q.l.Front() {
+		m := q.l.Remove(e).(*Message)
+		m.Attempts++
+		q.inflight[m.ID] = m
+		msgs = append(msgs, *m)
+	}
+	return msgs, nil
 }
 
-func (q *queue) SetIsConsuming(c bool) {
-	q.isConsuming = c
+func (q *queue) Ack(id uint) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.inflight, id)
+	return nil
 }
 
-func (q *queue) GetIsConsuming() bool {
-	return q.isConsuming
+func (q *queue) Fail(id uint, reason error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	m, ok := q.inflight[id]
+	if !ok {
+		return nil
+	}
+	delete(q.inflight, id)
+
+	if m.Attempts >= q.maxAttempts {
+		q.deadLetters = append(q.deadLetters, *m)
+		return nil
+	}
+	q.l.PushBack(m)
+	return nil
 }
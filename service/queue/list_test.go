@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+)
+
+func claimValues(t *testing.T, q Service, limit int) []Message {
+	t.Helper()
+	msgs, err := q.Claim(limit)
+	if err != nil {
+		t.Fatalf("Claim(%d) error = %v", limit, err)
+	}
+	return msgs
+}
+
+func TestListClaimInOrder(t *testing.T) {
+	q := NewQueueService()
+	for _, v := range []string{"a", "b", "c"} {
+		if err := q.Add(v); err != nil {
+			t.Fatalf("Add(%q) error = %v", v, err)
+		}
+	}
+
+	first := claimValues(t, q, 2)
+	if len(first) != 2 || first[0].Value != "a" || first[1].Value != "b" {
+		t.Fatalf("first claim = %+v, want a and b", first)
+	}
+	rest := claimValues(t, q, 10)
+	if len(rest) != 1 || rest[0].Value != "c" {
+		t.Fatalf("second claim = %+v, want c", rest)
+	}
+	if msgs := claimValues(t, q, 10); len(msgs) != 0 {
+		t.Fatalf("claimed messages are handed out again: %+v", msgs)
+	}
+}
+
+func TestListAckAndFail(t *testing.T) {
+	q := NewQueueService()
+	q.Add("acked")
+	q.Add("failed")
+
+	msgs := claimValues(t, q, 2)
+	if err := q.Ack(msgs[0].ID); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if err := q.Fail(msgs[1].ID, errors.New("boom")); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+
+	retried := claimValues(t, q, 10)
+	if len(retried) != 1 || retried[0].Value != "failed" || retried[0].Attempts != 2 {
+		t.Fatalf("retry = %+v, want the failed message on its second attempt", retried)
+	}
+}
+
+func TestListDeadLetter(t *testing.T) {
+	q := NewQueueService()
+	q.Add("poison")
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		msgs := claimValues(t, q, 1)
+		if len(msgs) != 1 {
+			t.Fatalf("attempt %d claimed %d messages, want 1", attempt, len(msgs))
+		}
+		q.Fail(msgs[0].ID, errors.New("boom"))
+	}
+
+	if msgs := claimValues(t, q, 1); len(msgs) != 0 {
+		t.Fatalf("message is retried after running out of attempts: %+v", msgs)
+	}
+	if dl := q.(*queue).deadLetters; len(dl) != 1 || dl[0].Value != "poison" {
+		t.Fatalf("dead letters = %+v, want the poison message", dl)
+	}
+}
+
+func TestListFailUnknown(t *testing.T) {
+	q := NewQueueService()
+	if err := q.Fail(42, errors.New("boom")); err != nil {
+		t.Fatalf("Fail() on an unknown message error = %v", err)
+	}
+	if msgs := claimValues(t, q, 1); len(msgs) != 0 {
+		t.Fatalf("unknown message was queued: %+v", msgs)
+	}
+}
@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/webuild-community/core/model"
+	"gorm.io/gorm"
+)
+
+// errTimedOut is recorded for messages whose consumer never acked or failed their last attempt
+var errTimedOut = errors.New("last attempt timed out")
+
+type pg struct {
+	db                *gorm.DB
+	newValue          func() interface{}
+	visibilityTimeout time.Duration
+	maxAttempts       int
+}
+
+// NewPGService returns a durable queue, newValue allocates the type payloads are decoded into
+func NewPGService(db *gorm.DB, newValue func() interface{}, visibilityTimeout time.Duration, maxAttempts int) Service {
+	return &pg{
+		db:                db,
+		newValue:          newValue,
+		visibilityTimeout: visibilityTimeout,
+		maxAttempts:       maxAttempts,
+	}
+}
+
+func (q *pg) WithTx(tx *gorm.DB) Service {
+	return &pg{
+		db:                tx,
+		newValue:          q.newValue,
+		visibilityTimeout: q.visibilityTimeout,
+		maxAttempts:       q.maxAttempts,
+	}
+}
+
+func (q *pg) Add(value interface{}) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return q.db.Create(&model.QueueMessage{Payload: string(payload), AvailableAt: time.Now()}).Error
+}
+
+func (q *pg) Claim(limit int) ([]Message, error) {
+	now := time.Now()
+	rows := []model.QueueMessage{}
+	// SKIP LOCKED lets several consumers claim disjoint batches without blocking each other
+	if err := q.db.Raw(`
+		UPDATE queue_message SET locked_until = ?, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM queue_message
+			WHERE available_at <= ? AND (locked_until IS NULL OR locked_until < ?)
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(q.visibilityTimeout), now, now, limit,
+	).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	msgs := make([]Message, 0, len(rows))
+	for _, r := range rows {
+		// a consumer that crashes or hangs never calls Fail, the claim itself spends the attempts
+		if r.Attempts > q.maxAttempts {
+			if err := q.deadLetter(r, errTimedOut); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		value := q.newValue()
+		if err := json.Unmarshal([]byte(r.Payload), value); err != nil {
+			// a payload that cannot be decoded will never succeed, dead-letter it right away
+			if err := q.deadLetter(r, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		msgs = append(msgs, Message{ID: r.ID, Attempts: r.Attempts, Value: value})
+	}
+	return msgs, nil
+}
+
+func (q *pg) Ack(id uint) error {
+	return q.db.Delete(&model.QueueMessage{}, id).Error
+}
+
+func (q *pg) Fail(id uint, reason error) error {
+	msg := model.QueueMessage{}
+	if err := q.db.First(&msg, id).Error; err != nil {
+		return err
+	}
+
+	if msg.Attempts >= q.maxAttempts {
+		return q.deadLetter(msg, reason)
+	}
+
+	backoff := time.Duration(msg.Attempts*msg.Attempts) * time.Second
+	return q.db.Model(&msg).Updates(map[string]interface{}{
+		"locked_until": nil,
+		"available_at": time.Now().Add(backoff),
+		"last_error":   reason.Error(),
+	}).Error
+}
+
+func (q *pg) deadLetter(msg model.QueueMessage, reason error) error {
+	return q.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&model.QueueDeadLetter{
+			MessageID: msg.ID,
+			Payload:   msg.Payload,
+			Attempts:  msg.Attempts,
+			LastError: reason.Error(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.QueueMessage{}, msg.ID).Error
+	})
+}
@@ -1,8 +1,20 @@
 package queue
 
+import "gorm.io/gorm"
+
+type Message struct {
+	ID       uint
+	Attempts int
+	Value    interface{}
+}
+
 type Service interface {
+	// WithTx returns a queue whose Add joins an outer db transaction
+	WithTx(tx *gorm.DB) Service
 	Add(interface{}) error
-	Consume() interface{}
-	SetIsConsuming(bool)
-	GetIsConsuming() bool
+	// Claim hides up to limit messages from other consumers until they are acked, failed or time out
+	Claim(limit int) ([]Message, error)
+	Ack(id uint) error
+	// Fail makes a message available again after a backoff, or dead-letters it once it ran out of attempts
+	Fail(id uint, reason error) error
 }
@@ -0,0 +1,32 @@
+package rule
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+type fileSvc struct {
+	rules []Rule
+}
+
+// NewFileService loads rules from a JSON file, DefaultRules are used when path is empty
+func NewFileService(path string) (Service, error) {
+	if path == "" {
+		return &fileSvc{rules: DefaultRules}, nil
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []Rule{}
+	if err := json.Unmarshal(buf, &rules); err != nil {
+		return nil, err
+	}
+	return &fileSvc{rules: rules}, nil
+}
+
+func (s *fileSvc) Evaluate(e Event) Result {
+	return evaluate(s.rules, e)
+}
@@ -0,0 +1,157 @@
+package rule
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventMessage         EventType = "message"
+	EventReactionAdded   EventType = "reaction_added"
+	EventReactionRemoved EventType = "reaction_removed"
+)
+
+type Role string
+
+const (
+	RoleAuthor   Role = "author"
+	RoleGiver    Role = "giver"
+	RoleReceiver Role = "receiver"
+)
+
+const (
+	ThreadReply    = "reply"
+	ThreadTopLevel = "top_level"
+)
+
+// Event is what the event handler knows about one user's part in a slack event
+type Event struct {
+	Type        EventType
+	Role        Role
+	ChannelID   string
+	Emoji       string
+	ThreadReply bool
+	TextLength  int
+	Time        time.Time
+}
+
+// As returns a copy of the event seen from the given role
+func (e Event) As(role Role) Event {
+	e.Role = role
+	return e
+}
+
+// Window restricts a rule to a date range, weekdays and a daily time range ("15:04")
+type Window struct {
+	From     *time.Time `json:"from"`
+	Until    *time.Time `json:"until"`
+	Weekdays []string   `json:"weekdays"`
+	Start    string     `json:"start"`
+	End      string     `json:"end"`
+}
+
+// Rule grants Points and/or scales the total by Multiplier when every condition matches,
+// empty conditions match anything
+type Rule struct {
+	Name       string    `json:"name"`
+	Event      EventType `json:"event"`
+	Role       Role      `json:"role"`
+	Channels   []string  `json:"channels"`
+	Emojis     []string  `json:"emojis"`
+	Thread     string    `json:"thread"`
+	MinLength  int       `json:"min_length"`
+	Points     int64     `json:"points"`
+	Multiplier float64   `json:"multiplier"`
+	Window     *Window   `json:"window"`
+}
+
+type Result struct {
+	Points int64
+	Rules  []string
+}
+
+type Service interface {
+	Evaluate(e Event) Result
+}
+
+// DefaultRules reproduces the historical hard-coded exp: +1 per message, +1 more for
+// long messages and ±1 for both sides of a reaction
+var DefaultRules = []Rule{
+	{Name: "message", Event: EventMessage, Role: RoleAuthor, Points: 1},
+	{Name: "long_message", Event: EventMessage, Role: RoleAuthor, MinLength: 51, Points: 1},
+	{Name: "reaction_added", Event: EventReactionAdded, Points: 1},
+	{Name: "reaction_removed", Event: EventReactionRemoved, Points: -1},
+}
+
+func evaluate(rules []Rule, e Event) Result {
+	res := Result{}
+	multiplier := 1.0
+	for _, r := range rules {
+		if !r.matches(e) {
+			continue
+		}
+		res.Points += r.Points
+		if r.Multiplier != 0 {
+			multiplier *= r.Multiplier
+		}
+		res.Rules = append(res.Rules, r.Name)
+	}
+
+	res.Points = int64(math.Round(float64(res.Points) * multiplier))
+	return res
+}
+
+func (r Rule) matches(e Event) bool {
+	if r.Event != "" && r.Event != e.Type {
+		return false
+	}
+	if r.Role != "" && r.Role != e.Role {
+		return false
+	}
+	if len(r.Channels) > 0 && !contains(r.Channels, e.ChannelID) {
+		return false
+	}
+	if len(r.Emojis) > 0 && !contains(r.Emojis, e.Emoji) {
+		return false
+	}
+	if r.Thread == ThreadReply && !e.ThreadReply || r.Thread == ThreadTopLevel && e.ThreadReply {
+		return false
+	}
+	if e.TextLength < r.MinLength {
+		return false
+	}
+	return r.Window == nil || r.Window.contains(e.Time)
+}
+
+func (w Window) contains(t time.Time) bool {
+	if w.From != nil && t.Before(*w.From) {
+		return false
+	}
+	if w.Until != nil && t.After(*w.Until) {
+		return false
+	}
+	if len(w.Weekdays) > 0 && !contains(w.Weekdays, strings.ToLower(t.Weekday().String())) {
+		return false
+	}
+
+	clock := t.Format("15:04")
+	if w.Start != "" && clock < w.Start {
+		return false
+	}
+	if w.End != "" && clock >= w.End {
+		return false
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
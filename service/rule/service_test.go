@@ -0,0 +1,173 @@
+package rule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestEvaluateDefaultRules(t *testing.T) {
+	monday := date("2021-06-07 10:00")
+	tests := []struct {
+		name  string
+		event Event
+		want  Result
+	}{
+		{
+			name:  "short message",
+			event: Event{Type: EventMessage, Role: RoleAuthor, TextLength: 10, Time: monday},
+			want:  Result{Points: 1, Rules: []string{"message"}},
+		},
+		{
+			name:  "long message",
+			event: Event{Type: EventMessage, Role: RoleAuthor, TextLength: 51, Time: monday},
+			want:  Result{Points: 2, Rules: []string{"message", "long_message"}},
+		},
+		{
+			name:  "message just below the long threshold",
+			event: Event{Type: EventMessage, Role: RoleAuthor, TextLength: 50, Time: monday},
+			want:  Result{Points: 1, Rules: []string{"message"}},
+		},
+		{
+			name:  "reaction added",
+			event: Event{Type: EventReactionAdded, Role: RoleReceiver, Emoji: "tada", Time: monday},
+			want:  Result{Points: 1, Rules: []string{"reaction_added"}},
+		},
+		{
+			name:  "reaction removed",
+			event: Event{Type: EventReactionRemoved, Role: RoleGiver, Emoji: "tada", Time: monday},
+			want:  Result{Points: -1, Rules: []string{"reaction_removed"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evaluate(DefaultRules, tt.event); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evaluate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateConditions(t *testing.T) {
+	monday := date("2021-06-07 10:00")
+	rules := []Rule{
+		{Name: "message", Event: EventMessage, Role: RoleAuthor, Points: 1},
+		{Name: "help_reply", Event: EventMessage, Role: RoleAuthor, Channels: []string{"CHELP"}, Thread: ThreadReply, Points: 2},
+		{Name: "announcement", Event: EventMessage, Channels: []string{"CNEWS"}, Thread: ThreadTopLevel, Points: 3},
+		{Name: "clap", Event: EventReactionAdded, Role: RoleReceiver, Emojis: []string{"clap", "pray"}, Points: 1},
+	}
+	tests := []struct {
+		name   string
+		event  Event
+		points int64
+		rules  []string
+	}{
+		{"reply in a listed channel", Event{Type: EventMessage, Role: RoleAuthor, ChannelID: "CHELP", ThreadReply: true}, 3, []string{"message", "help_reply"}},
+		{"top level message in a reply only channel", Event{Type: EventMessage, Role: RoleAuthor, ChannelID: "CHELP"}, 1, []string{"message"}},
+		{"reply in another channel", Event{Type: EventMessage, Role: RoleAuthor, ChannelID: "CRANDOM", ThreadReply: true}, 1, []string{"message"}},
+		{"top level in a top level only channel", Event{Type: EventMessage, Role: RoleAuthor, ChannelID: "CNEWS"}, 4, []string{"message", "announcement"}},
+		{"reply in a top level only channel", Event{Type: EventMessage, Role: RoleAuthor, ChannelID: "CNEWS", ThreadReply: true}, 1, []string{"message"}},
+		{"listed emoji for the receiver", Event{Type: EventReactionAdded, Role: RoleReceiver, Emoji: "pray"}, 1, []string{"clap"}},
+		{"listed emoji for the giver", Event{Type: EventReactionAdded, Role: RoleGiver, Emoji: "pray"}, 0, nil},
+		{"other emoji", Event{Type: EventReactionAdded, Role: RoleReceiver, Emoji: "eyes"}, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.event.Time = monday
+			got := evaluate(rules, tt.event)
+			if got.Points != tt.points || !reflect.DeepEqual(got.Rules, tt.rules) {
+				t.Errorf("evaluate() = %+v, want %d points from %v", got, tt.points, tt.rules)
+			}
+		})
+	}
+}
+
+func TestEvaluateWindows(t *testing.T) {
+	from, until := date("2021-06-01 00:00"), date("2021-06-30 00:00")
+	rules := []Rule{
+		{Name: "message", Event: EventMessage, Points: 3},
+		{Name: "weekend_double", Multiplier: 2, Window: &Window{Weekdays: []string{"saturday", "sunday"}, Start: "09:00", End: "18:00"}},
+		{Name: "june_bonus", Event: EventMessage, Points: 1, Window: &Window{From: &from, Until: &until}},
+	}
+	tests := []struct {
+		name   string
+		time   string
+		points int64
+	}{
+		{"weekday in june", "2021-06-07 10:00", 4},
+		{"saturday within hours", "2021-06-12 09:00", 8},
+		{"saturday at closing time", "2021-06-12 18:00", 4},
+		{"sunday before opening", "2021-06-13 08:59", 4},
+		{"weekday before june", "2021-05-31 10:00", 3},
+		{"saturday after june", "2021-07-03 10:00", 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := evaluate(rules, Event{Type: EventMessage, Role: RoleAuthor, Time: date(tt.time)})
+			if got.Points != tt.points {
+				t.Errorf("evaluate() = %d points from %v, want %d", got.Points, got.Rules, tt.points)
+			}
+		})
+	}
+}
+
+func TestEvaluateMultiplierRounding(t *testing.T) {
+	tests := []struct {
+		name       string
+		points     int64
+		multiplier float64
+		want       int64
+	}{
+		{"half rounds away from zero", 3, 1.5, 5},
+		{"negative half rounds away from zero", -1, 1.5, -2},
+		{"fraction below half rounds down", 1, 1.25, 1},
+		{"zero multiplier is ignored", 2, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := []Rule{
+				{Name: "points", Points: tt.points},
+				{Name: "boost", Multiplier: tt.multiplier},
+			}
+			if got := evaluate(rules, Event{Type: EventMessage}); got.Points != tt.want {
+				t.Errorf("evaluate() = %d, want %d", got.Points, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFileService(t *testing.T) {
+	s, err := NewFileService("")
+	if err != nil {
+		t.Fatalf("NewFileService(\"\") error = %v", err)
+	}
+	if got := s.Evaluate(Event{Type: EventMessage, Role: RoleAuthor, TextLength: 60}); got.Points != 2 {
+		t.Errorf("default rules gave %d points to a long message, want 2", got.Points)
+	}
+
+	s, err = NewFileService("../../exp_rules.example.json")
+	if err != nil {
+		t.Fatalf("cannot load the example rules: %v", err)
+	}
+	got := s.Evaluate(Event{Type: EventMessage, Role: RoleAuthor, ChannelID: "C0HELP", ThreadReply: true, Time: date("2021-06-07 10:00")})
+	if got.Points != 3 || !strings.Contains(strings.Join(got.Rules, ","), "help_reply") {
+		t.Errorf("example rules gave %+v to a help reply, want 3 points from help_reply", got)
+	}
+
+	if _, err := NewFileService("missing.json"); err == nil {
+		t.Error("NewFileService() with a missing file returned no error")
+	}
+}
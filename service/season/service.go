@@ -0,0 +1,41 @@
+package season
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/webuild-community/core/model"
+)
+
+var (
+	ErrSeasonActive   = errors.New("a season is already running")
+	ErrNoActiveSeason = errors.New("no season is running")
+)
+
+// NewRewardsFromEnv reads SEASON_REWARDS, the RDF paid to rank 1, 2, ... e.g. `100,50,25`
+func NewRewardsFromEnv() ([]float64, error) {
+	rewards := []float64{}
+	if os.Getenv("SEASON_REWARDS") == "" {
+		return rewards, nil
+	}
+	for _, v := range strings.Split(os.Getenv("SEASON_REWARDS"), ",") {
+		r, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || r < 0 || math.IsNaN(r) || math.IsInf(r, 0) {
+			return nil, fmt.Errorf("invalid SEASON_REWARDS entry %q", v)
+		}
+		rewards = append(rewards, r)
+	}
+	return rewards, nil
+}
+
+type Service interface {
+	// Current returns the running season, nil when there is none
+	Current() (*model.Season, error)
+	Start(name string) (*model.Season, error)
+	// End archives the standings from the exp earned since the season started and pays season rewards
+	End() (*model.Season, []model.SeasonStanding, error)
+}
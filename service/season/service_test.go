@@ -0,0 +1,61 @@
+package season
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestNewRewardsFromEnv(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    []float64
+		wantErr bool
+	}{
+		{env: "", want: []float64{}},
+		{env: "100", want: []float64{100}},
+		{env: "100, 50,25.5", want: []float64{100, 50, 25.5}},
+		{env: "100,0,10", want: []float64{100, 0, 10}},
+		{env: "100,,25", wantErr: true},
+		{env: "100,fifty,25", wantErr: true},
+		{env: "100,-5", wantErr: true},
+		{env: "NaN", wantErr: true},
+	}
+
+	old, ok := os.LookupEnv("SEASON_REWARDS")
+	defer func() {
+		if ok {
+			os.Setenv("SEASON_REWARDS", old)
+		} else {
+			os.Unsetenv("SEASON_REWARDS")
+		}
+	}()
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			os.Setenv("SEASON_REWARDS", tt.env)
+			got, err := NewRewardsFromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NewRewardsFromEnv() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewRewardsFromEnv() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewRewardsFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortStandings(t *testing.T) {
+	rows := []seasonExp{{"U3", 10}, {"U1", 30}, {"U4", 10}, {"U2", 30}}
+	sortStandings(rows)
+	want := []seasonExp{{"U1", 30}, {"U2", 30}, {"U3", 10}, {"U4", 10}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("sortStandings() = %v, want %v", rows, want)
+	}
+}
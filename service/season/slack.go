@@ -0,0 +1,147 @@
+package season
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/slackutil"
+	"github.com/webuild-community/core/service/transaction"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type slackSvc struct {
+	logger      *zap.Logger
+	db          *gorm.DB
+	slackClient *slack.Client
+	ledger      transaction.Service
+	rewards     []float64
+}
+
+// NewSlackService --
+func NewSlackService(logger *zap.Logger, db *gorm.DB, slackClient *slack.Client, ledger transaction.Service, rewards []float64) Service {
+	return &slackSvc{
+		logger:      logger,
+		db:          db,
+		slackClient: slackClient,
+		ledger:      ledger,
+		rewards:     rewards,
+	}
+}
+
+func (s *slackSvc) Current() (*model.Season, error) {
+	season := model.Season{}
+	if err := s.db.Where("ended_at IS NULL").Order("started_at DESC").First(&season).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &season, nil
+}
+
+func (s *slackSvc) Start(name string) (*model.Season, error) {
+	season := model.Season{Name: name, StartedAt: time.Now()}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.Season{}).Where("ended_at IS NULL").Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSeasonActive
+		}
+		return tx.Create(&season).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+func (s *slackSvc) End() (*model.Season, []model.SeasonStanding, error) {
+	season := model.Season{}
+	standings := []model.SeasonStanding{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("ended_at IS NULL").Order("started_at DESC").First(&season).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoActiveSeason
+			}
+			return err
+		}
+
+		// the same exp history `$top season` ranks members on
+		now := time.Now()
+		rows := []seasonExp{}
+		if err := tx.Model(&model.ExpEvent{}).
+			Select("user_id AS id, SUM(delta) AS season_exp").
+			Where("created_at >= ? AND created_at < ? AND event_type <> ?", season.StartedAt, now, model.ExpEventOpening).
+			Group("user_id").Having("SUM(delta) > 0").
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+		sortStandings(rows)
+
+		ledger := s.ledger.WithTx(tx)
+		for i, r := range rows {
+			rank := i + 1
+			if i > 0 && r.SeasonExp == rows[i-1].SeasonExp {
+				rank = standings[i-1].Rank
+			}
+			st := model.SeasonStanding{SeasonID: season.ID, UserID: r.ID, Rank: rank, Exp: r.SeasonExp}
+			if rank <= len(s.rewards) && s.rewards[rank-1] > 0 {
+				st.Reward = s.rewards[rank-1]
+				if _, err := ledger.Credit(r.ID, st.Reward, transaction.Entry{
+					Reason:         model.ReasonSeasonReward,
+					IdempotencyKey: fmt.Sprintf("season:%d:%s", season.ID, r.ID),
+					Memo:           fmt.Sprintf("#%d in %s", rank, season.Name),
+				}); err != nil {
+					return err
+				}
+			}
+			standings = append(standings, st)
+		}
+		if len(standings) > 0 {
+			if err := tx.CreateInBatches(standings, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		season.EndedAt = &now
+		return tx.Model(&season).Update("ended_at", now).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, st := range standings {
+		if st.Reward == 0 {
+			continue
+		}
+		slackutil.DM(s.slackClient, s.logger, st.UserID, slack.MsgOptionText(
+			fmt.Sprintf("*%s is over*\nYou finished #%d with %d exp and earned `%v` RDF", season.Name, st.Rank, st.Exp, st.Reward),
+			false,
+		))
+	}
+
+	return &season, standings, nil
+}
+
+type seasonExp struct {
+	ID        string
+	SeasonExp int64
+}
+
+func sortStandings(rows []seasonExp) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].SeasonExp != rows[j].SeasonExp {
+			return rows[i].SeasonExp > rows[j].SeasonExp
+		}
+		return rows[i].ID < rows[j].ID
+	})
+}
@@ -0,0 +1,24 @@
+package slackutil
+
+import (
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// DM sends a direct message to userID, failures are logged and returned
+func DM(client *slack.Client, logger *zap.Logger, userID string, options ...slack.MsgOption) error {
+	channel, _, _, err := client.OpenConversation(&slack.OpenConversationParameters{
+		Users:    []string{userID},
+		ReturnIM: true,
+	})
+	if err != nil {
+		logger.Error("open direct message failed", zap.Error(err), zap.String("user_id", userID))
+		return err
+	}
+
+	if _, _, _, err := client.SendMessage(channel.ID, options...); err != nil {
+		logger.Error("send message failed", zap.Error(err), zap.String("user_id", userID))
+		return err
+	}
+	return nil
+}
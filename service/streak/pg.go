@@ -0,0 +1,119 @@
+package streak
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/abuse"
+	"github.com/webuild-community/core/service/rule"
+	"gorm.io/gorm"
+)
+
+type pg struct {
+	db          *gorm.DB
+	abuseSvc    abuse.Service
+	multipliers []Multiplier
+}
+
+// NewPGService --
+func NewPGService(db *gorm.DB, abuseSvc abuse.Service, multipliers []Multiplier) Service {
+	return &pg{db: db, abuseSvc: abuseSvc, multipliers: multipliers}
+}
+
+func (s *pg) WithTx(tx *gorm.DB) Service {
+	return &pg{db: tx, abuseSvc: s.abuseSvc, multipliers: s.multipliers}
+}
+
+func (s *pg) Track(u model.User, events []model.ExpEvent) ([]model.ExpEvent, error) {
+	loc := location(u.TZ)
+	days := map[string]bool{}
+	for _, e := range events {
+		if e.EventType == string(rule.EventMessage) {
+			days[e.CreatedAt.In(loc).Format(dateLayout)] = true
+		}
+	}
+	if len(days) == 0 {
+		return events, nil
+	}
+
+	dates := make([]string, 0, len(days))
+	for d := range days {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+
+	streak, longest, last := u.StreakDays, u.LongestStreak, u.LastActiveDate
+	for _, d := range dates {
+		switch {
+		case d <= last:
+			// already counted, or a late delivery from before the last active day
+			continue
+		case last != "" && d == nextDay(last):
+			streak++
+		default:
+			streak = 1
+		}
+		last = d
+		if streak > longest {
+			longest = streak
+		}
+	}
+
+	if last != u.LastActiveDate {
+		if err := s.db.Model(&model.User{}).Where("id = ?", u.ID).Updates(map[string]interface{}{
+			"streak_days":      streak,
+			"longest_streak":   longest,
+			"last_active_date": last,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	factor := s.factor(streak)
+	if factor == 1 {
+		return events, nil
+	}
+
+	res := make([]model.ExpEvent, 0, len(events))
+	for _, e := range events {
+		if e.EventType == string(rule.EventMessage) && e.Delta > 0 {
+			// the delta was already capped, the bonus has to fit in what is left of the daily cap
+			bonus := int64(math.Round(float64(e.Delta)*factor)) - e.Delta
+			if bonus = s.abuseSvc.Cap(u.ID, bonus, e.CreatedAt); bonus != 0 {
+				e.Delta += bonus
+				e.Rule = joinRule(e.Rule, fmt.Sprintf("streak_%dd", streak))
+			}
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+// factor returns the multiplier of the highest tier the streak reached
+func (s *pg) factor(streak int) float64 {
+	factor := 1.0
+	for _, m := range s.multipliers {
+		if streak >= m.Days {
+			factor = m.Factor
+		}
+	}
+	return factor
+}
+
+func nextDay(date string) string {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, 0, 1).Format(dateLayout)
+}
+
+func joinRule(rules, r string) string {
+	if rules == "" {
+		return r
+	}
+	return rules + "," + r
+}
@@ -0,0 +1,71 @@
+package streak
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webuild-community/core/model"
+	"gorm.io/gorm"
+)
+
+const dateLayout = "2006-01-02"
+
+// Multiplier scales message exp once a streak reaches Days
+type Multiplier struct {
+	Days   int
+	Factor float64
+}
+
+// NewMultipliersFromEnv reads STREAK_MULTIPLIERS, e.g. `3:1.1,7:1.25,30:1.5`
+func NewMultipliersFromEnv() []Multiplier {
+	ms := []Multiplier{}
+	for _, v := range strings.Split(os.Getenv("STREAK_MULTIPLIERS"), ",") {
+		parts := strings.SplitN(strings.TrimSpace(v), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		days, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		factor, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			continue
+		}
+		ms = append(ms, Multiplier{Days: days, Factor: factor})
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Days < ms[j].Days })
+	return ms
+}
+
+type Service interface {
+	// WithTx returns a streak service whose writes join an outer db transaction
+	WithTx(tx *gorm.DB) Service
+	// Track advances the user's streak with their message events and returns the
+	// events with streak bonuses applied
+	Track(u model.User, events []model.ExpEvent) ([]model.ExpEvent, error)
+}
+
+// Current returns the streak still alive at now, a streak breaks after a full day without messages
+func Current(u model.User, now time.Time) int {
+	if u.LastActiveDate == "" {
+		return 0
+	}
+	today := now.In(location(u.TZ)).Format(dateLayout)
+	yesterday := now.In(location(u.TZ)).AddDate(0, 0, -1).Format(dateLayout)
+	if u.LastActiveDate != today && u.LastActiveDate != yesterday {
+		return 0
+	}
+	return u.StreakDays
+}
+
+func location(tz string) *time.Location {
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "" {
+		return time.UTC
+	}
+	return loc
+}
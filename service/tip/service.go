@@ -0,0 +1,56 @@
+package tip
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/webuild-community/core/model"
+)
+
+var (
+	ErrInvalidArgs        = errors.New("usage: `@user <amount> [reason]`")
+	ErrInvalidAmount      = errors.New("amount must be a positive number")
+	ErrSelfTip            = errors.New("you cannot tip yourself")
+	ErrBotTip             = errors.New("you cannot tip a bot")
+	ErrDailyLimitExceeded = errors.New("you have reached your daily tipping limit")
+	ErrInsufficient       = errors.New("you do not have enough RDF")
+)
+
+var mentionRe = regexp.MustCompile(`^<@([A-Z0-9]+)(\|[^>]*)?>$`)
+
+type Service interface {
+	Send(key, fromUserID, toUserID string, amount float64, reason string) (*model.JournalEntry, error)
+}
+
+// IsRejection reports whether err is a rule violation that should be shown to the sender
+func IsRejection(err error) bool {
+	for _, e := range []error{ErrInvalidArgs, ErrInvalidAmount, ErrSelfTip, ErrBotTip, ErrDailyLimitExceeded, ErrInsufficient} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseArgs parses `<@U123> 10 reason` as sent by slack for both $tip and /tip
+func ParseArgs(text string) (toUserID string, amount float64, reason string, err error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return "", 0, "", ErrInvalidArgs
+	}
+
+	m := mentionRe.FindStringSubmatch(fields[0])
+	if m == nil {
+		return "", 0, "", ErrInvalidArgs
+	}
+
+	amount, err = strconv.ParseFloat(fields[1], 64)
+	if err != nil || amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return "", 0, "", ErrInvalidAmount
+	}
+
+	return m[1], amount, strings.Join(fields[2:], " "), nil
+}
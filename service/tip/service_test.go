@@ -0,0 +1,51 @@
+package tip
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		text    string
+		to      string
+		amount  float64
+		reason  string
+		wantErr error
+	}{
+		{text: "<@U0123> 10", to: "U0123", amount: 10},
+		{text: "<@U0123|alice> 2.5 thanks for the review", to: "U0123", amount: 2.5, reason: "thanks for the review"},
+		{text: "  <@U0123>   1   spaced   out ", to: "U0123", amount: 1, reason: "spaced out"},
+		{text: "", wantErr: ErrInvalidArgs},
+		{text: "<@U0123>", wantErr: ErrInvalidArgs},
+		{text: "@alice 10", wantErr: ErrInvalidArgs},
+		{text: "10 <@U0123>", wantErr: ErrInvalidArgs},
+		{text: "<@U0123> ten", wantErr: ErrInvalidAmount},
+		{text: "<@U0123> 0", wantErr: ErrInvalidAmount},
+		{text: "<@U0123> -5", wantErr: ErrInvalidAmount},
+		{text: "<@U0123> NaN", wantErr: ErrInvalidAmount},
+		{text: "<@U0123> Inf", wantErr: ErrInvalidAmount},
+		{text: "<@U0123> 1e400", wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			to, amount, reason, err := ParseArgs(tt.text)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ParseArgs(%q) error = %v, want %v", tt.text, err, tt.wantErr)
+				}
+				if !IsRejection(err) {
+					t.Errorf("ParseArgs(%q) error %v is not shown to the sender", tt.text, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseArgs(%q) error = %v", tt.text, err)
+			}
+			if to != tt.to || amount != tt.amount || reason != tt.reason {
+				t.Errorf("ParseArgs(%q) = %q, %v, %q, want %q, %v, %q", tt.text, to, amount, reason, tt.to, tt.amount, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package tip
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/slackutil"
+	"github.com/webuild-community/core/service/transaction"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type slackSvc struct {
+	logger      *zap.Logger
+	db          *gorm.DB
+	slackClient *slack.Client
+	ledger      transaction.Service
+	dailyLimit  float64
+}
+
+// NewSlackService --
+func NewSlackService(logger *zap.Logger, db *gorm.DB, slackClient *slack.Client, ledger transaction.Service, dailyLimit float64) Service {
+	return &slackSvc{
+		logger:      logger,
+		db:          db,
+		slackClient: slackClient,
+		ledger:      ledger,
+		dailyLimit:  dailyLimit,
+	}
+}
+
+func (s *slackSvc) Send(key, fromUserID, toUserID string, amount float64, reason string) (*model.JournalEntry, error) {
+	if amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return nil, ErrInvalidAmount
+	}
+	if fromUserID == toUserID {
+		return nil, ErrSelfTip
+	}
+
+	sUser, err := s.slackClient.GetUserInfo(toUserID)
+	if err != nil {
+		s.logger.Error("cannot get slack user info", zap.Error(err), zap.String("user_id", toUserID))
+		return nil, err
+	}
+	if sUser.IsBot {
+		return nil, ErrBotTip
+	}
+
+	var entry *model.JournalEntry
+	idempotencyKey := fmt.Sprintf("tip:%s:%s", fromUserID, key)
+	replayed := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// serialize the daily limit check of one sender
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "tip:"+fromUserID).Error; err != nil {
+			return err
+		}
+
+		// a retried request already counts towards today's tips, the ledger hands its entry back
+		var count int64
+		if err := tx.Model(&model.JournalEntry{}).Where("idempotency_key = ?", idempotencyKey).Count(&count).Error; err != nil {
+			return err
+		}
+		replayed = count > 0
+
+		if s.dailyLimit > 0 && !replayed {
+			sent, err := sentToday(tx, fromUserID)
+			if err != nil {
+				return err
+			}
+			if sent+amount > s.dailyLimit {
+				return ErrDailyLimitExceeded
+			}
+		}
+
+		entry, err = s.ledger.WithTx(tx).Transfer(fromUserID, toUserID, amount, transaction.Entry{
+			Reason:         model.ReasonTip,
+			IdempotencyKey: idempotencyKey,
+			Memo:           reason,
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, transaction.ErrInsufficientBalance) {
+			return nil, ErrInsufficient
+		}
+		return nil, err
+	}
+	// both users were told the first time
+	if replayed {
+		return entry, nil
+	}
+
+	note := ""
+	if reason != "" {
+		note = fmt.Sprintf("\n> %s", reason)
+	}
+	balance, err := s.ledger.Balance(fromUserID, time.Now())
+	if err != nil {
+		s.logger.Error("cannot get balance", zap.Error(err), zap.String("user_id", fromUserID))
+	}
+	slackutil.DM(s.slackClient, s.logger, fromUserID, slack.MsgOptionText(
+		fmt.Sprintf("*Tip sent*\nYou sent `%v` RDF to <@%s>%s\nBalance: `%v` RDF", amount, toUserID, note, balance),
+		false,
+	))
+	slackutil.DM(s.slackClient, s.logger, toUserID, slack.MsgOptionText(
+		fmt.Sprintf("*Tip received*\n<@%s> sent you `%v` RDF%s", fromUserID, amount, note),
+		false,
+	))
+
+	return entry, nil
+}
+
+// sentToday sums the RDF a user has tipped since the start of the day
+func sentToday(tx *gorm.DB, userID string) (float64, error) {
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var sent float64
+	err := tx.Model(&model.Posting{}).
+		Select("COALESCE(SUM(posting.amount), 0)").
+		Joins("JOIN journal_entry ON journal_entry.id = posting.journal_entry_id").
+		Where("journal_entry.reason = ?", model.ReasonTip).
+		Where("posting.account_id = ? AND posting.side = ?", model.UserAccountID(userID), model.PostingDebit).
+		Where("posting.created_at >= ?", startOfDay).
+		Scan(&sent).Error
+	return sent, err
+}
@@ -1,7 +1,14 @@
 package transaction
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/webuild-community/core/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type pg struct {
@@ -12,3 +19,278 @@ type pg struct {
 func NewPGService(db *gorm.DB) Service {
 	return &pg{db: db}
 }
+
+func (s *pg) WithTx(tx *gorm.DB) Service {
+	return &pg{db: tx}
+}
+
+type leg struct {
+	accountID string
+	side      model.PostingSide
+	amount    float64
+}
+
+func (s *pg) Credit(userID string, amount float64, e Entry) (*model.JournalEntry, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	return s.post(e, nil, []string{userID}, nil,
+		leg{accountID: model.TreasuryAccountID, side: model.PostingDebit, amount: amount},
+		leg{accountID: model.UserAccountID(userID), side: model.PostingCredit, amount: amount},
+	)
+}
+
+func (s *pg) Debit(userID string, amount float64, e Entry) (*model.JournalEntry, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	return s.post(e, nil, []string{userID}, map[string]float64{userID: amount},
+		leg{accountID: model.UserAccountID(userID), side: model.PostingDebit, amount: amount},
+		leg{accountID: model.TreasuryAccountID, side: model.PostingCredit, amount: amount},
+	)
+}
+
+func (s *pg) Transfer(fromUserID, toUserID string, amount float64, e Entry) (*model.JournalEntry, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	return s.post(e, nil, []string{fromUserID, toUserID}, map[string]float64{fromUserID: amount},
+		leg{accountID: model.UserAccountID(fromUserID), side: model.PostingDebit, amount: amount},
+		leg{accountID: model.UserAccountID(toUserID), side: model.PostingCredit, amount: amount},
+	)
+}
+
+// Reverse posts the mirror of an entry, reversals are not checked against balances
+// so that admins can always undo a bad entry
+func (s *pg) Reverse(entryID uint, e Entry) (*model.JournalEntry, error) {
+	original := model.JournalEntry{}
+	if err := s.db.Preload("Postings").First(&original, "id = ?", entryID).Error; err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.db.Model(&model.JournalEntry{}).Where("reversal_of_id = ?", entryID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrAlreadyReversed
+	}
+
+	userIDs := []string{}
+	for _, p := range original.Postings {
+		account := model.Account{}
+		if err := s.db.First(&account, "id = ?", p.AccountID).Error; err != nil {
+			return nil, err
+		}
+		if account.Type == model.AccountUser {
+			userIDs = append(userIDs, account.UserID)
+		}
+	}
+
+	if e.Reason == "" {
+		e.Reason = model.ReasonReversal
+	}
+	return s.post(e, &entryID, userIDs, nil, mirror(original.Postings)...)
+}
+
+// mirror returns the legs that undo postings
+func mirror(postings []model.Posting) []leg {
+	legs := make([]leg, 0, len(postings))
+	for _, p := range postings {
+		side := model.PostingDebit
+		if p.Side == model.PostingDebit {
+			side = model.PostingCredit
+		}
+		legs = append(legs, leg{accountID: p.AccountID, side: side, amount: p.Amount})
+	}
+	return legs
+}
+
+func (s *pg) Balance(userID string, at time.Time) (float64, error) {
+	return balance(s.db, model.UserAccountID(userID), at)
+}
+
+// Backfill records an opening entry for users whose balance predates the ledger
+func (s *pg) Backfill() error {
+	users := []model.User{}
+	if err := s.db.
+		Where("balance <> 0").
+		Where("NOT EXISTS (SELECT 1 FROM posting WHERE posting.account_id = 'user:' || \"user\".id)").
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		e := Entry{
+			Reason:         model.ReasonOpeningBalance,
+			IdempotencyKey: fmt.Sprintf("opening:%s", u.ID),
+		}
+		if _, err := s.post(e, nil, []string{u.ID}, nil, openingLegs(u.ID, u.Balance)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openingLegs move a balance that predates the ledger between the treasury and the user
+func openingLegs(userID string, balance float64) []leg {
+	if balance > 0 {
+		return []leg{
+			{accountID: model.TreasuryAccountID, side: model.PostingDebit, amount: balance},
+			{accountID: model.UserAccountID(userID), side: model.PostingCredit, amount: balance},
+		}
+	}
+	return []leg{
+		{accountID: model.UserAccountID(userID), side: model.PostingDebit, amount: -balance},
+		{accountID: model.TreasuryAccountID, side: model.PostingCredit, amount: -balance},
+	}
+}
+
+// post writes a balanced journal entry, it locks every user involved, checks that
+// users in spend can afford it and refreshes the cached user balance
+func (s *pg) post(e Entry, reversalOf *uint, userIDs []string, spend map[string]float64, legs ...leg) (*model.JournalEntry, error) {
+	if e.IdempotencyKey == "" {
+		return nil, errors.New("missing idempotency key")
+	}
+
+	var debits, credits float64
+	for _, l := range legs {
+		if l.side == model.PostingDebit {
+			debits += l.amount
+		} else {
+			credits += l.amount
+		}
+	}
+	if debits != credits {
+		return nil, errors.New("unbalanced journal entry")
+	}
+
+	entry := model.JournalEntry{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// lock users in a stable order so two transfers never deadlock each other
+		ids := append([]string{}, userIDs...)
+		sort.Strings(ids)
+		for _, id := range ids {
+			if err := tx.FirstOrCreate(&model.User{}, map[string]interface{}{"id": id}).Error; err != nil {
+				return err
+			}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&model.User{}, "id = ?", id).Error; err != nil {
+				return err
+			}
+		}
+
+		existing := model.JournalEntry{}
+		err := tx.Preload("Postings").First(&existing, "idempotency_key = ?", e.IdempotencyKey).Error
+		if err == nil {
+			// a retry gets the entry it already posted, anything else reusing the key is a bug
+			if !sameEntry(existing, e, reversalOf, legs) {
+				return ErrIdempotencyMismatch
+			}
+			entry = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		now := time.Now()
+		if err := afford(spend, func(accountID string) (float64, error) {
+			return balance(tx, accountID, now)
+		}); err != nil {
+			return err
+		}
+
+		if err := ensureAccount(tx, model.Account{ID: model.TreasuryAccountID, Type: model.AccountSystem}); err != nil {
+			return err
+		}
+		for _, id := range userIDs {
+			if err := ensureAccount(tx, model.Account{ID: model.UserAccountID(id), Type: model.AccountUser, UserID: id}); err != nil {
+				return err
+			}
+		}
+
+		entry = model.JournalEntry{
+			Reason:         e.Reason,
+			IdempotencyKey: e.IdempotencyKey,
+			Memo:           e.Memo,
+			ReversalOfID:   reversalOf,
+			CreatedAt:      now,
+		}
+		for _, l := range legs {
+			entry.Postings = append(entry.Postings, model.Posting{
+				AccountID: l.accountID,
+				Side:      l.side,
+				Amount:    l.amount,
+				CreatedAt: now,
+			})
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+
+		for _, id := range userIDs {
+			b, err := balance(tx, model.UserAccountID(id), now)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&model.User{}).Where("id = ?", id).Update("balance", b).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return &entry, err
+}
+
+// afford checks that every user in spend has at least the amount they spend
+func afford(spend map[string]float64, balanceOf func(accountID string) (float64, error)) error {
+	for userID, amount := range spend {
+		b, err := balanceOf(model.UserAccountID(userID))
+		if err != nil {
+			return err
+		}
+		if b < amount {
+			return ErrInsufficientBalance
+		}
+	}
+	return nil
+}
+
+// sameEntry reports whether an entry found by its idempotency key records the operation being posted
+func sameEntry(existing model.JournalEntry, e Entry, reversalOf *uint, legs []leg) bool {
+	if existing.Reason != e.Reason || len(existing.Postings) != len(legs) {
+		return false
+	}
+	if (existing.ReversalOfID == nil) != (reversalOf == nil) ||
+		(reversalOf != nil && *existing.ReversalOfID != *reversalOf) {
+		return false
+	}
+
+	want := make(map[leg]int, len(legs))
+	for _, l := range legs {
+		want[l]++
+	}
+	for _, p := range existing.Postings {
+		l := leg{accountID: p.AccountID, side: p.Side, amount: p.Amount}
+		if want[l] == 0 {
+			return false
+		}
+		want[l]--
+	}
+	return true
+}
+
+func ensureAccount(tx *gorm.DB, account model.Account) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error
+}
+
+func balance(db *gorm.DB, accountID string, at time.Time) (float64, error) {
+	var b float64
+	err := db.Model(&model.Posting{}).
+		Select("COALESCE(SUM(CASE WHEN side = ? THEN amount ELSE -amount END), 0)", model.PostingCredit).
+		Where("account_id = ? AND created_at <= ?", accountID, at).
+		Scan(&b).Error
+	return b, err
+}
@@ -0,0 +1,151 @@
+package transaction
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/webuild-community/core/model"
+)
+
+func TestPostRejectsBadEntries(t *testing.T) {
+	s := &pg{}
+	e := Entry{Reason: model.ReasonAdjustment, IdempotencyKey: "test"}
+
+	if _, err := s.Credit("U1", 0, e); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Credit(0) error = %v, want ErrInvalidAmount", err)
+	}
+	if _, err := s.Debit("U1", -5, e); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Debit(-5) error = %v, want ErrInvalidAmount", err)
+	}
+	if _, err := s.Transfer("U1", "U2", 0, e); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Transfer(0) error = %v, want ErrInvalidAmount", err)
+	}
+	if _, err := s.post(Entry{Reason: model.ReasonAdjustment}, nil, nil, nil); err == nil {
+		t.Error("post() without an idempotency key succeeded")
+	}
+	if _, err := s.post(e, nil, nil, nil,
+		leg{accountID: model.TreasuryAccountID, side: model.PostingDebit, amount: 10},
+		leg{accountID: model.UserAccountID("U1"), side: model.PostingCredit, amount: 9},
+	); err == nil {
+		t.Error("post() of an unbalanced entry succeeded")
+	}
+}
+
+func TestAfford(t *testing.T) {
+	balances := map[string]float64{model.UserAccountID("U1"): 10, model.UserAccountID("U2"): 3}
+	balanceOf := func(accountID string) (float64, error) {
+		return balances[accountID], nil
+	}
+
+	tests := []struct {
+		spend map[string]float64
+		want  error
+	}{
+		{spend: nil},
+		{spend: map[string]float64{"U1": 10}},
+		{spend: map[string]float64{"U1": 10.5}, want: ErrInsufficientBalance},
+		{spend: map[string]float64{"U1": 1, "U2": 4}, want: ErrInsufficientBalance},
+		{spend: map[string]float64{"U3": 1}, want: ErrInsufficientBalance},
+	}
+	for _, tt := range tests {
+		if err := afford(tt.spend, balanceOf); !errors.Is(err, tt.want) {
+			t.Errorf("afford(%v) error = %v, want %v", tt.spend, err, tt.want)
+		}
+	}
+
+	boom := errors.New("boom")
+	if err := afford(map[string]float64{"U1": 1}, func(string) (float64, error) { return 0, boom }); !errors.Is(err, boom) {
+		t.Errorf("afford() error = %v, want the lookup error", err)
+	}
+}
+
+func TestSameEntry(t *testing.T) {
+	reversalOf := uint(7)
+	existing := model.JournalEntry{
+		Reason:         model.ReasonTip,
+		IdempotencyKey: "tip:1",
+		Postings: []model.Posting{
+			{AccountID: model.UserAccountID("U1"), Side: model.PostingDebit, Amount: 5},
+			{AccountID: model.UserAccountID("U2"), Side: model.PostingCredit, Amount: 5},
+		},
+	}
+	legs := []leg{
+		{accountID: model.UserAccountID("U2"), side: model.PostingCredit, amount: 5},
+		{accountID: model.UserAccountID("U1"), side: model.PostingDebit, amount: 5},
+	}
+	e := Entry{Reason: model.ReasonTip, IdempotencyKey: "tip:1", Memo: "memos may differ"}
+
+	if !sameEntry(existing, e, nil, legs) {
+		t.Error("a retry of the same transfer is reported as a different entry")
+	}
+	if sameEntry(existing, Entry{Reason: model.ReasonAdjustment, IdempotencyKey: "tip:1"}, nil, legs) {
+		t.Error("a different reason is reported as the same entry")
+	}
+	if sameEntry(existing, e, &reversalOf, legs) {
+		t.Error("a reversal is reported as the same entry as a plain transfer")
+	}
+
+	amount := []leg{
+		{accountID: model.UserAccountID("U1"), side: model.PostingDebit, amount: 6},
+		{accountID: model.UserAccountID("U2"), side: model.PostingCredit, amount: 6},
+	}
+	if sameEntry(existing, e, nil, amount) {
+		t.Error("a different amount is reported as the same entry")
+	}
+	account := []leg{
+		{accountID: model.UserAccountID("U1"), side: model.PostingDebit, amount: 5},
+		{accountID: model.UserAccountID("U3"), side: model.PostingCredit, amount: 5},
+	}
+	if sameEntry(existing, e, nil, account) {
+		t.Error("a different account is reported as the same entry")
+	}
+}
+
+func TestMirror(t *testing.T) {
+	postings := []model.Posting{
+		{AccountID: model.UserAccountID("U1"), Side: model.PostingDebit, Amount: 5},
+		{AccountID: model.TreasuryAccountID, Side: model.PostingCredit, Amount: 5},
+	}
+	want := []leg{
+		{accountID: model.UserAccountID("U1"), side: model.PostingCredit, amount: 5},
+		{accountID: model.TreasuryAccountID, side: model.PostingDebit, amount: 5},
+	}
+
+	got := mirror(postings)
+	if len(got) != len(want) {
+		t.Fatalf("mirror() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("mirror()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOpeningLegs(t *testing.T) {
+	tests := []struct {
+		balance float64
+		want    []leg
+	}{
+		{balance: 12, want: []leg{
+			{accountID: model.TreasuryAccountID, side: model.PostingDebit, amount: 12},
+			{accountID: model.UserAccountID("U1"), side: model.PostingCredit, amount: 12},
+		}},
+		{balance: -3, want: []leg{
+			{accountID: model.UserAccountID("U1"), side: model.PostingDebit, amount: 3},
+			{accountID: model.TreasuryAccountID, side: model.PostingCredit, amount: 3},
+		}},
+	}
+
+	for _, tt := range tests {
+		got := openingLegs("U1", tt.balance)
+		if len(got) != len(tt.want) {
+			t.Fatalf("openingLegs(%v) = %+v, want %+v", tt.balance, got, tt.want)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("openingLegs(%v)[%d] = %+v, want %+v", tt.balance, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
@@ -1,4 +1,34 @@
 package transaction
 
+import (
+	"errors"
+	"time"
+
+	"github.com/webuild-community/core/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAmount       = errors.New("amount must be positive")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrAlreadyReversed     = errors.New("entry is already reversed")
+	ErrIdempotencyMismatch = errors.New("idempotency key was already used for a different entry")
+)
+
+// Entry describes the journal entry a ledger operation records
+type Entry struct {
+	Reason         model.ReasonCode
+	IdempotencyKey string
+	Memo           string
+}
+
 type Service interface {
+	// WithTx returns a ledger bound to an outer db transaction
+	WithTx(tx *gorm.DB) Service
+	Credit(userID string, amount float64, e Entry) (*model.JournalEntry, error)
+	Debit(userID string, amount float64, e Entry) (*model.JournalEntry, error)
+	Transfer(fromUserID, toUserID string, amount float64, e Entry) (*model.JournalEntry, error)
+	Reverse(entryID uint, e Entry) (*model.JournalEntry, error)
+	Balance(userID string, at time.Time) (float64, error)
+	Backfill() error
 }
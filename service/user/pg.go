@@ -1,17 +1,32 @@
 package user
 
 import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/webuild-community/core/model"
+	"github.com/webuild-community/core/service/level"
+	"github.com/webuild-community/core/service/rule"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type pg struct {
-	db *gorm.DB
+	db    *gorm.DB
+	curve level.Service
+	rules rule.Service
 }
 
 // NewPGService --
-func NewPGService(db *gorm.DB) Service {
-	return &pg{db: db}
+func NewPGService(db *gorm.DB, curve level.Service, rules rule.Service) Service {
+	return &pg{db: db, curve: curve, rules: rules}
+}
+
+func (s *pg) WithTx(tx *gorm.DB) Service {
+	return &pg{db: tx, curve: s.curve, rules: s.rules}
 }
 
 func (s *pg) Find(id string) (model.User, error) {
@@ -19,21 +34,212 @@ func (s *pg) Find(id string) (model.User, error) {
 	return user, s.db.First(&user, "id = ?", id).Error
 }
 
-func (s *pg) Update(id string, changes map[string]interface{}) (model.User, bool, error) {
+func (s *pg) Update(id string, changes map[string]interface{}) (model.User, error) {
 	user := model.User{}
 	if err := s.db.FirstOrCreate(&user, map[string]interface{}{"id": id}).Error; err != nil {
-		return user, false, err
+		return user, err
+	}
+	return user, s.db.Model(&user).Updates(changes).Error
+}
+
+func (s *pg) FindMany(ids []string) (map[string]model.User, error) {
+	users := []model.User{}
+	if err := s.db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]model.User, len(users))
+	for _, u := range users {
+		res[u.ID] = u
+	}
+	return res, nil
+}
+
+func (s *pg) AddExp(events []model.ExpEvent) ([]ExpChange, error) {
+	deltas := map[string]int64{}
+	active := map[string]bool{}
+	for i, e := range events {
+		if e.Points != 0 {
+			events[i].Scale = float64(e.Delta) / float64(e.Points)
+		}
+		deltas[e.UserID] += e.Delta
+		// receiving reactions or kudos does not make a user active
+		if e.Role != string(rule.RoleReceiver) {
+			active[e.UserID] = true
+		}
+	}
+	if len(deltas) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(deltas))
+	for id := range deltas {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	changes := make([]ExpChange, 0, len(ids))
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		newUsers := make([]model.User, 0, len(ids))
+		for _, id := range ids {
+			newUsers = append(newUsers, model.User{ID: id})
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&newUsers).Error; err != nil {
+			return err
+		}
+		if err := tx.CreateInBatches(events, 500).Error; err != nil {
+			return err
+		}
+
+		users := []model.User{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", ids).Order("id").Find(&users).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		values := make([]string, 0, len(users))
+		args := make([]interface{}, 0, len(users)*5)
+		for _, u := range users {
+			newExp := u.Exp + deltas[u.ID]
+			c := ExpChange{UserID: u.ID, OldExp: u.Exp, NewExp: newExp, OldLevel: u.Level, NewLevel: s.curve.Level(newExp)}
+			changes = append(changes, c)
+
+			lastActiveAt := u.LastActiveAt
+			if active[u.ID] {
+				lastActiveAt = now
+			}
+			values = append(values, expTuple)
+			args = append(args, c.UserID, c.NewExp, c.NewLevel, u.RankingScore+deltas[u.ID], lastActiveAt)
+		}
+
+		return updateExp(tx, values, args)
+	})
+
+	return changes, err
+}
+
+func (s *pg) Recompute(id string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		q := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&model.User{})
+		if id != "" {
+			q = q.Where("id = ?", id)
+		}
+		users := []model.User{}
+		if err := q.Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := s.rerate(tx, id); err != nil {
+			return err
+		}
+		exps, err := sumExp(tx, id)
+		if err != nil {
+			return err
+		}
+
+		values := make([]string, 0, len(users))
+		args := make([]interface{}, 0, len(users)*5)
+		for _, u := range users {
+			// the ranking score keeps its decay and only follows the correction
+			rankingScore := u.RankingScore + exps[u.ID] - u.Exp
+			values = append(values, expTuple)
+			args = append(args, u.ID, exps[u.ID], s.curve.Level(exps[u.ID]), rankingScore, u.LastActiveAt)
+		}
+
+		return updateExp(tx, values, args)
+	})
+}
+
+// rerate runs the current rules over the inputs of rated events, their delta keeps its original scale
+func (s *pg) rerate(tx *gorm.DB, id string) error {
+	q := tx.Where("scale <> 0")
+	if id != "" {
+		q = q.Where("user_id = ?", id)
 	}
 
-	isLevelUp := false
-	if exp, ok := changes["exp"].(int64); ok {
-		user.Exp += exp
-		isLevelUp = user.IsLevelUp()
-		changes["exp"] = user.Exp
-		if isLevelUp {
-			changes["level"] = user.Level + 1
+	events := []model.ExpEvent{}
+	return q.FindInBatches(&events, 500, func(_ *gorm.DB, _ int) error {
+		values := []string{}
+		args := []interface{}{}
+		for _, e := range events {
+			res := s.rules.Evaluate(rule.Event{
+				Type:        rule.EventType(e.EventType),
+				Role:        rule.Role(e.Role),
+				ChannelID:   e.ChannelID,
+				Emoji:       e.Emoji,
+				ThreadReply: e.ThreadReply,
+				TextLength:  e.TextLength,
+				Time:        e.CreatedAt,
+			})
+			if res.Points == e.Points {
+				continue
+			}
+			values = append(values, "(?::bigint, ?::bigint, ?::bigint, ?)")
+			args = append(args, e.ID, int64(math.Round(float64(res.Points)*e.Scale)), res.Points, rerule(e.Rule, res.Rules))
+		}
+		if len(values) == 0 {
+			return nil
+		}
+
+		return tx.Exec(fmt.Sprintf(`
+			UPDATE exp_event SET delta = v.delta, points = v.points, rule = v.rule
+			FROM (VALUES %s) AS v(id, delta, points, rule)
+			WHERE exp_event.id = v.id`, strings.Join(values, ", ")), args...).Error
+	}).Error
+}
+
+// rerule swaps the rule names of an event for rules, keeping the channel and streak adjustments
+func rerule(old string, rules []string) string {
+	names := append([]string{}, rules...)
+	for _, r := range strings.Split(old, ",") {
+		if strings.HasPrefix(r, "channel_x") || strings.HasPrefix(r, "streak_") {
+			names = append(names, r)
 		}
 	}
+	return strings.Join(names, ",")
+}
+
+func (s *pg) Backfill() error {
+	return s.db.Exec(`
+		INSERT INTO exp_event (user_id, event_type, delta, created_at)
+		SELECT id, ?, exp, now() FROM "user"
+		WHERE exp <> 0 AND NOT EXISTS (SELECT 1 FROM exp_event WHERE exp_event.user_id = "user".id)`,
+		model.ExpEventOpening,
+	).Error
+}
+
+// sumExp totals exp events per user, for one user or everyone when id is empty
+func sumExp(tx *gorm.DB, id string) (map[string]int64, error) {
+	totals := []struct {
+		UserID string
+		Exp    int64
+	}{}
+	q := tx.Model(&model.ExpEvent{}).Select("user_id, SUM(delta) AS exp").Group("user_id")
+	if id != "" {
+		q = q.Where("user_id = ?", id)
+	}
+	if err := q.Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	exps := make(map[string]int64, len(totals))
+	for _, t := range totals {
+		exps[t.UserID] = t.Exp
+	}
+	return exps, nil
+}
+
+const expTuple = "(?, ?::bigint, ?::bigint, ?::bigint, ?::timestamptz)"
 
-	return user, isLevelUp, s.db.Model(&user).Updates(changes).Error
+// updateExp sets exp, level, ranking score and last activity from expTuple values
+func updateExp(tx *gorm.DB, values []string, args []interface{}) error {
+	return tx.Exec(fmt.Sprintf(`
+		UPDATE "user" SET exp = v.exp, level = v.level,
+			ranking_score = v.ranking_score, last_active_at = v.last_active_at, updated_at = now()
+		FROM (VALUES %s) AS v(id, exp, level, ranking_score, last_active_at)
+		WHERE "user".id = v.id`, strings.Join(values, ", ")), args...).Error
 }
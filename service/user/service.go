@@ -1,8 +1,31 @@
 package user
 
-import "github.com/webuild-community/core/model"
+import (
+	"github.com/webuild-community/core/model"
+	"gorm.io/gorm"
+)
+
+// ExpChange describes the effect of exp events on a user
+type ExpChange struct {
+	UserID   string
+	OldExp   int64
+	NewExp   int64
+	OldLevel uint
+	NewLevel uint
+}
 
 type Service interface {
+	// WithTx returns a user service bound to an outer db transaction
+	WithTx(tx *gorm.DB) Service
 	Find(id string) (model.User, error)
-	Update(id string, changes map[string]interface{}) (model.User, bool, error)
+	FindMany(ids []string) (map[string]model.User, error)
+	// Update creates the user when needed and sets the given columns, exp only changes through AddExp
+	Update(id string, changes map[string]interface{}) (model.User, error)
+	// AddExp records exp events and applies them to their users in a single statement
+	AddExp(events []model.ExpEvent) ([]ExpChange, error)
+	// Recompute rates exp events again with the current rules and rebuilds exp and level
+	// from history, for one user or everyone when id is empty
+	Recompute(id string) error
+	// Backfill records an opening exp event for users whose exp predates the history
+	Backfill() error
 }